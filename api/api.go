@@ -3,10 +3,14 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hbd-cli/structs"
+	"io"
 	"net/http"
+	neturl "net/url"
 	"strings"
+	"time"
 )
 
 // Define a common error type for API responses
@@ -14,21 +18,93 @@ type APIError struct {
 	Error string `json:"error"`
 }
 
+// maxErrorBodySize is how much of an error response body is kept in error messages
+const maxErrorBodySize = 512
+
+// NetworkError is returned when the request could not reach the server
+type NetworkError struct {
+	Err error
+	// Endpoint is the path of the request, e.g. /api/me
+	Endpoint string
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("error making request: %v", e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// IsNetworkError reports whether the error means the server could not be reached
+func IsNetworkError(err error) bool {
+	var networkErr *NetworkError
+	return errors.As(err, &networkErr)
+}
+
+// StatusError is returned when the server answers with an error status
+type StatusError struct {
+	StatusCode int
+	Status     string
+	// Message is the error sent by the API, Body is whatever else the server sent (e.g. a proxy)
+	Message string
+	Body    string
+	// Endpoint is the path of the request, e.g. /api/me
+	Endpoint string
+}
+
+func (e *StatusError) Error() string {
+	switch {
+	case e.Message != "":
+		return fmt.Sprintf("error: %s", e.Message)
+	case e.Body != "":
+		return fmt.Sprintf("error: %s: %s", e.Status, e.Body)
+	default:
+		return fmt.Sprintf("error: %s", e.Status)
+	}
+}
+
+// transport sends every request, it can be wrapped to record, replay or log them
+var transport http.RoundTripper = http.DefaultTransport
+
+// SetTransport replaces the transport used for every request
+func SetTransport(rt http.RoundTripper) {
+	transport = rt
+}
+
+// joinURL joins the path of an endpoint to the base URL of the service, keeping its path prefix,
+// e.g. https://tools.example.com/hbd/ and api/me give https://tools.example.com/hbd/api/me
+func joinURL(base, path string) string {
+	joined, err := neturl.JoinPath(base, path)
+	if err != nil {
+		// The request fails with a clearer error for the invalid URL
+		return strings.TrimRight(base, "/") + "/" + path
+	}
+	return joined
+}
+
 // Helper function to handle JSON marshalling, HTTP requests, and response decoding
 func makeRequest(method, url string, payload interface{}, token string, result interface{}, tokenDuration int) error {
+	_, err := sendRequest(method, url, payload, token, result, tokenDuration, 0)
+	return err
+}
+
+// sendRequest is makeRequest also returning the headers of the response, when there is one.
+// The request fails with a network error after timeout, if it's not zero.
+func sendRequest(method, url string, payload interface{}, token string, result interface{}, tokenDuration int, timeout time.Duration) (http.Header, error) {
 	var reqBody []byte
 	var err error
 
 	if payload != nil {
 		reqBody, err = json.Marshal(payload)
 		if err != nil {
-			return fmt.Errorf("error encoding request body: %v", err)
+			return nil, fmt.Errorf("error encoding request body: %v", err)
 		}
 	}
 
 	req, err := http.NewRequest(method, url, bytes.NewBuffer(reqBody))
 	if err != nil {
-		return fmt.Errorf("error creating request: %v", err)
+		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -41,32 +117,45 @@ func makeRequest(method, url string, payload interface{}, token string, result i
 		req.Header.Set("X-Jwt-Token-Duration", fmt.Sprintf("%d", tokenDuration))
 	}
 
-	client := &http.Client{}
+	client := &http.Client{Transport: transport, Timeout: timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("error making request: %v", err)
+		// A request missing from a cassette says nothing about the server
+		if errors.Is(err, ErrNotRecorded) {
+			return nil, err
+		}
+		return nil, &NetworkError{Err: err, Endpoint: req.URL.Path}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+		// Not an API error (e.g. from a proxy), report the status and whatever the server sent
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Endpoint: req.URL.Path}
 		var apiErr APIError
-		json.NewDecoder(resp.Body).Decode(&apiErr)
-		return fmt.Errorf("error: %s", apiErr.Error)
+		if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error == "" {
+			statusErr.Body = strings.TrimSpace(string(body))
+			return resp.Header, statusErr
+		}
+
+		statusErr.Message = apiErr.Error
+		return resp.Header, statusErr
 	}
 
 	if result != nil {
 		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			return fmt.Errorf("error decoding response: %v", err)
+			return resp.Header, fmt.Errorf("error decoding response: %v", err)
 		}
 	}
 
-	return nil
+	return resp.Header, nil
 }
 
 // Add a new birthday
 func AddBirthday(url, token string, birthday structs.BirthdayNameDateAdd) (*structs.BirthdayFull, error) {
 	var birthdayFull structs.BirthdayFull
-	endpoint := fmt.Sprintf("%s/api/add-birthday", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/add-birthday")
 	err := makeRequest("POST", endpoint, birthday, token, &birthdayFull, 0)
 	return &birthdayFull, err
 }
@@ -74,7 +163,7 @@ func AddBirthday(url, token string, birthday structs.BirthdayNameDateAdd) (*stru
 // Force check birthdays
 func CheckBirthdays(url, token string) (*structs.Success, error) {
 	var success structs.Success
-	endpoint := fmt.Sprintf("%s/api/check-birthdays", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/check-birthdays")
 	err := makeRequest("PATCH", endpoint, nil, token, &success, 0)
 	return &success, err
 }
@@ -82,7 +171,7 @@ func CheckBirthdays(url, token string) (*structs.Success, error) {
 // Delete a birthday
 func DeleteBirthday(url, token string, birthday structs.BirthdayNameDateModify) (*structs.Success, error) {
 	var success structs.Success
-	endpoint := fmt.Sprintf("%s/api/delete-birthday", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/delete-birthday")
 	err := makeRequest("DELETE", endpoint, birthday, token, &success, 0)
 	return &success, err
 }
@@ -90,7 +179,7 @@ func DeleteBirthday(url, token string, birthday structs.BirthdayNameDateModify)
 // Delete a user
 func DeleteUser(url, token string) (*structs.Success, error) {
 	var success structs.Success
-	endpoint := fmt.Sprintf("%s/api/delete-user", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/delete-user")
 	err := makeRequest("DELETE", endpoint, nil, token, &success, 0)
 	return &success, err
 }
@@ -98,7 +187,7 @@ func DeleteUser(url, token string) (*structs.Success, error) {
 // Generate a new password
 func GeneratePassword(url string) (*structs.Password, error) {
 	var password structs.Password
-	endpoint := fmt.Sprintf("%s/api/generate-password", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/generate-password")
 	err := makeRequest("GET", endpoint, nil, "", &password, 0)
 	return &password, err
 }
@@ -106,31 +195,64 @@ func GeneratePassword(url string) (*structs.Password, error) {
 // Check service readiness
 func CheckHealth(url string) (*structs.Ready, error) {
 	var ready structs.Ready
-	endpoint := fmt.Sprintf("%s/api/health", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/health")
 	err := makeRequest("GET", endpoint, nil, "", &ready, 0)
 	return &ready, err
 }
 
+// HealthProbe is the result of a health check along with how it went
+type HealthProbe struct {
+	Ready   structs.Ready
+	Latency time.Duration
+	// ServerTime is the time from the Date header of the response, zero if the server didn't send it
+	ServerTime time.Time
+}
+
+// ProbeHealth checks the health of the service, measuring the latency and reading the server time.
+// The probe is returned along with the error when the server answered with an error status.
+func ProbeHealth(url string, timeout time.Duration) (*HealthProbe, error) {
+	var probe HealthProbe
+	endpoint := joinURL(url, "api/health")
+
+	start := time.Now()
+	header, err := sendRequest("GET", endpoint, nil, "", &probe.Ready, 0, timeout)
+	probe.Latency = time.Since(start)
+
+	if header == nil {
+		return nil, err
+	}
+	if date, dateErr := http.ParseTime(header.Get("Date")); dateErr == nil {
+		probe.ServerTime = date
+	}
+
+	return &probe, err
+}
+
 // Login a user
 func Login(url string, user structs.LoginRequest, tokenDuration int) (*structs.LoginSuccess, error) {
 	var loginSuccess structs.LoginSuccess
-	endpoint := fmt.Sprintf("%s/api/login", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/login")
 	err := makeRequest("POST", endpoint, user, "", &loginSuccess, tokenDuration)
 	return &loginSuccess, err
 }
 
 // Get user data
 func GetUserData(url, token string) (*structs.UserData, error) {
+	return GetUserDataWithTimeout(url, token, 0)
+}
+
+// GetUserDataWithTimeout is GetUserData giving up after timeout, for when an answer is needed quickly
+func GetUserDataWithTimeout(url, token string, timeout time.Duration) (*structs.UserData, error) {
 	var userData structs.UserData
-	endpoint := fmt.Sprintf("%s/api/me", strings.TrimRight(url, "/"))
-	err := makeRequest("GET", endpoint, nil, token, &userData, 0)
+	endpoint := joinURL(url, "api/me")
+	_, err := sendRequest("GET", endpoint, nil, token, &userData, 0, timeout)
 	return &userData, err
 }
 
 // Modify a birthday
 func ModifyBirthday(url, token string, birthday structs.BirthdayNameDateModify) (*structs.Success, error) {
 	var success structs.Success
-	endpoint := fmt.Sprintf("%s/api/modify-birthday", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/modify-birthday")
 	err := makeRequest("PUT", endpoint, birthday, token, &success, 0)
 	return &success, err
 }
@@ -138,7 +260,7 @@ func ModifyBirthday(url, token string, birthday structs.BirthdayNameDateModify)
 // Modify a user's details (including email or password)
 func ModifyUserWithEmail(url, token string, user structs.ModifyUserRequest, tokenDuration int) (*structs.LoginSuccess, error) {
 	var loginSuccess structs.LoginSuccess
-	endpoint := fmt.Sprintf("%s/api/modify-user", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/modify-user")
 	err := makeRequest("PUT", endpoint, user, token, &loginSuccess, tokenDuration)
 	return &loginSuccess, err
 }
@@ -146,7 +268,7 @@ func ModifyUserWithEmail(url, token string, user structs.ModifyUserRequest, toke
 // Modify a user's details (excluding email or password)
 func ModifyUserWithoutEmail(url, token string, user structs.ModifyUserRequest) (*structs.UserData, error) {
 	var userData structs.UserData
-	endpoint := fmt.Sprintf("%s/api/modify-user", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/modify-user")
 	err := makeRequest("PUT", endpoint, user, token, &userData, 0)
 	return &userData, err
 }
@@ -154,7 +276,7 @@ func ModifyUserWithoutEmail(url, token string, user structs.ModifyUserRequest) (
 // Register a new user
 func Register(url string, user structs.RegisterRequest, tokenDuration int) (*structs.LoginSuccess, error) {
 	var loginSuccess structs.LoginSuccess
-	endpoint := fmt.Sprintf("%s/api/register", strings.TrimRight(url, "/"))
+	endpoint := joinURL(url, "api/register")
 	err := makeRequest("POST", endpoint, user, "", &loginSuccess, tokenDuration)
 	return &loginSuccess, err
 }
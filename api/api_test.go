@@ -0,0 +1,405 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"hbd-cli/structs"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordedRequest is what the contract server received
+type recordedRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// newContractServer starts a server that records the request and answers with the given status and body
+func newContractServer(t *testing.T, status int, contentType, body string) (*httptest.Server, *recordedRequest) {
+	t.Helper()
+	recorded := &recordedRequest{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorded.Method = r.Method
+		recorded.Path = r.URL.Path
+		recorded.Header = r.Header.Clone()
+		recorded.Body, _ = io.ReadAll(r.Body)
+
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, recorded
+}
+
+// contract describes the request an api function must send and the response it must decode
+type contract struct {
+	name     string
+	call     func(url string) (interface{}, error)
+	method   string
+	path     string
+	token    string
+	duration string
+	body     string
+	response string
+	want     interface{}
+}
+
+var birthday = structs.BirthdayFull{ID: 7, Name: "John Doe", Date: "2000-02-29"}
+
+var userData = structs.UserData{
+	ID:                1,
+	TelegramBotAPIKey: "270485614:AAHfiqksKZ8WmR2zSjiQ7jd8Eud81ggE3e-3",
+	TelegramUserID:    "123456789",
+	ReminderTime:      "15:04",
+	Timezone:          "America/New_York",
+	Birthdays:         []structs.BirthdayFull{birthday},
+}
+
+var loginSuccess = structs.LoginSuccess{
+	Token:             "new-token",
+	TelegramBotAPIKey: userData.TelegramBotAPIKey,
+	TelegramUserID:    userData.TelegramUserID,
+	ReminderTime:      userData.ReminderTime,
+	Timezone:          userData.Timezone,
+	Birthdays:         userData.Birthdays,
+}
+
+var modifyUserRequest = structs.ModifyUserRequest{
+	NewEmail:             "new@hbd.lotiguere.com",
+	NewPassword:          "new-password",
+	NewReminderTime:      "09:00",
+	NewTimezone:          "Europe/Madrid",
+	NewTelegramBotAPIKey: "key",
+	NewTelegramUserID:    "42",
+}
+
+func mustJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+var contracts = []contract{
+	{
+		name: "AddBirthday",
+		call: func(url string) (interface{}, error) {
+			return AddBirthday(url, "tok", structs.BirthdayNameDateAdd{Name: "John Doe", Date: "2000-02-29"})
+		},
+		method:   "POST",
+		path:     "/api/add-birthday",
+		token:    "tok",
+		body:     `{"name":"John Doe","date":"2000-02-29"}`,
+		response: mustJSON(birthday),
+		want:     &birthday,
+	},
+	{
+		name:     "CheckBirthdays",
+		call:     func(url string) (interface{}, error) { return CheckBirthdays(url, "tok") },
+		method:   "PATCH",
+		path:     "/api/check-birthdays",
+		token:    "tok",
+		response: `{"success":true}`,
+		want:     &structs.Success{Success: true},
+	},
+	{
+		name: "DeleteBirthday",
+		call: func(url string) (interface{}, error) {
+			return DeleteBirthday(url, "tok", structs.BirthdayNameDateModify{ID: 7})
+		},
+		method:   "DELETE",
+		path:     "/api/delete-birthday",
+		token:    "tok",
+		body:     `{"id":7,"name":"","date":""}`,
+		response: `{"success":true}`,
+		want:     &structs.Success{Success: true},
+	},
+	{
+		name:     "DeleteUser",
+		call:     func(url string) (interface{}, error) { return DeleteUser(url, "tok") },
+		method:   "DELETE",
+		path:     "/api/delete-user",
+		token:    "tok",
+		response: `{"success":true}`,
+		want:     &structs.Success{Success: true},
+	},
+	{
+		name:     "GeneratePassword",
+		call:     func(url string) (interface{}, error) { return GeneratePassword(url) },
+		method:   "GET",
+		path:     "/api/generate-password",
+		response: `{"password":"s3cret"}`,
+		want:     &structs.Password{Password: "s3cret"},
+	},
+	{
+		name:     "CheckHealth",
+		call:     func(url string) (interface{}, error) { return CheckHealth(url) },
+		method:   "GET",
+		path:     "/api/health",
+		response: `{"status":"ready"}`,
+		want:     &structs.Ready{Status: "ready"},
+	},
+	{
+		name: "ProbeHealth",
+		call: func(url string) (interface{}, error) {
+			probe, err := ProbeHealth(url, 0)
+			if probe == nil {
+				return nil, err
+			}
+			return &probe.Ready, err
+		},
+		method:   "GET",
+		path:     "/api/health",
+		response: `{"status":"ready"}`,
+		want:     &structs.Ready{Status: "ready"},
+	},
+	{
+		name: "Login",
+		call: func(url string) (interface{}, error) {
+			return Login(url, structs.LoginRequest{Email: "user@hbd.lotiguere.com", Password: "pw"}, 720)
+		},
+		method:   "POST",
+		path:     "/api/login",
+		duration: "720",
+		body:     `{"email":"user@hbd.lotiguere.com","password":"pw"}`,
+		response: mustJSON(loginSuccess),
+		want:     &loginSuccess,
+	},
+	{
+		name:     "GetUserData",
+		call:     func(url string) (interface{}, error) { return GetUserData(url, "tok") },
+		method:   "GET",
+		path:     "/api/me",
+		token:    "tok",
+		response: mustJSON(userData),
+		want:     &userData,
+	},
+	{
+		name: "ModifyBirthday",
+		call: func(url string) (interface{}, error) {
+			return ModifyBirthday(url, "tok", structs.BirthdayNameDateModify{ID: 7, Name: "Jane Doe", Date: "2000-03-01"})
+		},
+		method:   "PUT",
+		path:     "/api/modify-birthday",
+		token:    "tok",
+		body:     `{"id":7,"name":"Jane Doe","date":"2000-03-01"}`,
+		response: `{"success":true}`,
+		want:     &structs.Success{Success: true},
+	},
+	{
+		name:     "ModifyUserWithEmail",
+		call:     func(url string) (interface{}, error) { return ModifyUserWithEmail(url, "tok", modifyUserRequest, 48) },
+		method:   "PUT",
+		path:     "/api/modify-user",
+		token:    "tok",
+		duration: "48",
+		body:     mustJSON(modifyUserRequest),
+		response: mustJSON(loginSuccess),
+		want:     &loginSuccess,
+	},
+	{
+		name:     "ModifyUserWithoutEmail",
+		call:     func(url string) (interface{}, error) { return ModifyUserWithoutEmail(url, "tok", modifyUserRequest) },
+		method:   "PUT",
+		path:     "/api/modify-user",
+		token:    "tok",
+		body:     mustJSON(modifyUserRequest),
+		response: mustJSON(userData),
+		want:     &userData,
+	},
+	{
+		name: "Register",
+		call: func(url string) (interface{}, error) {
+			return Register(url, structs.RegisterRequest{
+				Email:             "user@hbd.lotiguere.com",
+				Password:          "pw",
+				ReminderTime:      "15:04",
+				Timezone:          "America/New_York",
+				TelegramBotAPIKey: "key",
+				TelegramUserID:    "42",
+			}, 1)
+		},
+		method:   "POST",
+		path:     "/api/register",
+		duration: "1",
+		body:     `{"email":"user@hbd.lotiguere.com","password":"pw","reminder_time":"15:04","timezone":"America/New_York","telegram_bot_api_key":"key","telegram_user_id":"42"}`,
+		response: mustJSON(loginSuccess),
+		want:     &loginSuccess,
+	},
+}
+
+func TestContracts(t *testing.T) {
+	for _, c := range contracts {
+		t.Run(c.name, func(t *testing.T) {
+			ts, recorded := newContractServer(t, http.StatusOK, "application/json", c.response)
+
+			got, err := c.call(ts.URL)
+			if err != nil {
+				t.Fatalf("%s() error = %v", c.name, err)
+			}
+
+			// Request line
+			if recorded.Method != c.method || recorded.Path != c.path {
+				t.Errorf("request = %s %s, want %s %s", recorded.Method, recorded.Path, c.method, c.path)
+			}
+
+			// Headers
+			if ct := recorded.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			wantAuth := ""
+			if c.token != "" {
+				wantAuth = "Bearer " + c.token
+			}
+			if auth := recorded.Header.Get("Authorization"); auth != wantAuth {
+				t.Errorf("Authorization = %q, want %q", auth, wantAuth)
+			}
+			if duration := recorded.Header.Get("X-Jwt-Token-Duration"); duration != c.duration {
+				t.Errorf("X-Jwt-Token-Duration = %q, want %q", duration, c.duration)
+			}
+
+			// Body
+			if c.body == "" {
+				if len(recorded.Body) != 0 {
+					t.Errorf("body = %s, want none", recorded.Body)
+				}
+			} else {
+				var gotBody, wantBody interface{}
+				if err := json.Unmarshal(recorded.Body, &gotBody); err != nil {
+					t.Fatalf("body %q is not JSON: %v", recorded.Body, err)
+				}
+				json.Unmarshal([]byte(c.body), &wantBody)
+				if !reflect.DeepEqual(gotBody, wantBody) {
+					t.Errorf("body = %s, want %s", recorded.Body, c.body)
+				}
+			}
+
+			// Decoded response
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("%s() = %+v, want %+v", c.name, got, c.want)
+			}
+		})
+	}
+}
+
+func TestContractErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantErr     string
+	}{
+		{"api error", http.StatusBadRequest, "application/json", `{"error":"invalid date"}`, "error: invalid date"},
+		{"unauthorized api error", http.StatusUnauthorized, "application/json", `{"error":"token expired"}`, "error: token expired"},
+		{"non-JSON error body", http.StatusBadGateway, "text/html", "<html>Bad Gateway</html>\n", "error: 502 Bad Gateway: <html>Bad Gateway</html>"},
+		{"empty error body", http.StatusServiceUnavailable, "", "", "error: 503 Service Unavailable"},
+		{"JSON error without message", http.StatusInternalServerError, "application/json", `{"detail":"boom"}`, `error: 500 Internal Server Error: {"detail":"boom"}`},
+		{"undecodable success body", http.StatusOK, "application/json", "not json", "error decoding response"},
+	}
+
+	for _, tt := range tests {
+		for _, c := range contracts {
+			t.Run(tt.name+"/"+c.name, func(t *testing.T) {
+				ts, _ := newContractServer(t, tt.status, tt.contentType, tt.body)
+
+				_, err := c.call(ts.URL)
+				if err == nil {
+					t.Fatalf("%s() error = nil, want %q", c.name, tt.wantErr)
+				}
+				if !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("%s() error = %q, want prefix %q", c.name, err, tt.wantErr)
+				}
+				if IsNetworkError(err) {
+					t.Errorf("%s() error %q is a network error", c.name, err)
+				}
+
+				// Error statuses are typed so the exit code can depend on them
+				var statusErr *StatusError
+				if tt.status != http.StatusOK && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.status || statusErr.Endpoint != c.path) {
+					t.Errorf("%s() error = %#v, want a StatusError with status %d for %s", c.name, err, tt.status, c.path)
+				}
+			})
+		}
+	}
+}
+
+func TestContractNetworkError(t *testing.T) {
+	ts, _ := newContractServer(t, http.StatusOK, "", "")
+	url := ts.URL
+	ts.Close()
+
+	for _, c := range contracts {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := c.call(url)
+			var networkErr *NetworkError
+			if !errors.As(err, &networkErr) || networkErr.Endpoint != c.path {
+				t.Errorf("%s() error = %#v, want a network error for %s", c.name, err, c.path)
+			}
+		})
+	}
+}
+
+func TestContractTrailingSlash(t *testing.T) {
+	ts, recorded := newContractServer(t, http.StatusOK, "application/json", `{"status":"ready"}`)
+
+	if _, err := CheckHealth(ts.URL + "/"); err != nil {
+		t.Fatalf("CheckHealth() error = %v", err)
+	}
+	if recorded.Path != "/api/health" {
+		t.Errorf("path = %s, want /api/health", recorded.Path)
+	}
+}
+
+func TestProbeHealthServerTime(t *testing.T) {
+	ts, _ := newContractServer(t, http.StatusOK, "application/json", `{"status":"ready"}`)
+
+	probe, err := ProbeHealth(ts.URL, 0)
+	if err != nil {
+		t.Fatalf("ProbeHealth() error = %v", err)
+	}
+	if probe.Latency <= 0 {
+		t.Errorf("ProbeHealth() latency = %v, want it measured", probe.Latency)
+	}
+	// The Date header has a one second resolution
+	if skew := time.Since(probe.ServerTime); skew < -time.Second || skew > 2*time.Second {
+		t.Errorf("ProbeHealth() server time = %v, want about now", probe.ServerTime)
+	}
+}
+
+func TestProbeHealthTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	t.Cleanup(ts.Close)
+
+	if _, err := ProbeHealth(ts.URL, 20*time.Millisecond); !IsNetworkError(err) {
+		t.Errorf("ProbeHealth() error = %v, want a network error", err)
+	}
+}
+
+func TestContractPathPrefix(t *testing.T) {
+	ts, recorded := newContractServer(t, http.StatusOK, "application/json", `{"status":"ready"}`)
+
+	for _, base := range []string{ts.URL + "/hbd", ts.URL + "/hbd/"} {
+		if _, err := CheckHealth(base); err != nil {
+			t.Fatalf("CheckHealth(%s) error = %v", base, err)
+		}
+		if recorded.Path != "/hbd/api/health" {
+			t.Errorf("CheckHealth(%s) path = %s, want /hbd/api/health", base, recorded.Path)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CassetteVersion is the version of the cassette file format
+const CassetteVersion = 1
+
+// ErrNotRecorded is returned when replaying a request that is not in the cassette
+var ErrNotRecorded = errors.New("request not recorded in the cassette")
+
+// Cassette is a recording of the requests made by the CLI and the responses it got
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single request and its response, or the error if the server couldn't be reached
+type Interaction struct {
+	Request  RecordedRequest   `json:"request"`
+	Response *RecordedResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// RecordedRequest is a request as stored in a cassette, with its secrets redacted
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response as stored in a cassette, with its secrets redacted
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("error decoding cassette %s: %v", path, err)
+	}
+	if cassette.Version != CassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d", cassette.Version)
+	}
+
+	return &cassette, nil
+}
+
+// Save writes the cassette to a file, readable only by the user
+func (c *Cassette) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Recorder is a transport that saves every request and response to a cassette file.
+// The file is written after each request, so it's complete even if the command fails halfway.
+type Recorder struct {
+	mu       sync.Mutex
+	path     string
+	next     http.RoundTripper
+	cassette Cassette
+}
+
+// NewRecorder creates a recorder writing to path, sending the requests through next
+func NewRecorder(path string, next http.RoundTripper) *Recorder {
+	return &Recorder{path: path, next: next, cassette: Cassette{Version: CassetteVersion}}
+}
+
+// RoundTrip sends the request and records it along with its response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: RedactHeader(req.Header),
+			Body:   string(RedactBody(reqBody)),
+		},
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		interaction.Error = err.Error()
+	} else {
+		respBody, readErr := readBody(&resp.Body)
+		if readErr != nil {
+			resp.Body.Close()
+			return nil, readErr
+		}
+		interaction.Response = &RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       string(RedactBody(respBody)),
+		}
+
+		// The redacted body doesn't have the original length
+		interaction.Response.Header.Del("Content-Length")
+	}
+
+	// Requests can run concurrently, e.g. when deleting several birthdays
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if saveErr := r.cassette.Save(r.path); saveErr != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, fmt.Errorf("error saving cassette: %v", saveErr)
+	}
+
+	return resp, err
+}
+
+// Replayer is a transport that answers requests with the responses recorded in a cassette,
+// without any network access. Each interaction is served once, in the order they were recorded.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer creates a replayer serving the interactions of the cassette
+func NewReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{interactions: cassette.Interactions, used: make([]bool, len(cassette.Interactions))}
+}
+
+// RoundTrip answers the request with the first unused interaction with the same method and path.
+// The host is ignored, so a cassette can be replayed whatever server it was recorded against.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.interactions {
+		if r.used[i] || interaction.Request.Method != req.Method || !samePath(interaction.Request.URL, req) {
+			continue
+		}
+		r.used[i] = true
+
+		if interaction.Response == nil {
+			return nil, errors.New(interaction.Error)
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewBufferString(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, ErrNotRecorded
+}
+
+// samePath compares the path and query of a recorded URL with a request
+func samePath(recorded string, req *http.Request) bool {
+	recordedURL, err := url.Parse(recorded)
+	if err != nil {
+		return false
+	}
+	return recordedURL.RequestURI() == req.URL.RequestURI()
+}
+
+// readBody reads a request or response body and replaces it with a copy so it can still be read
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+
+	return data, nil
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"hbd-cli/structs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useTransport replaces the transport for the duration of a test
+func useTransport(t *testing.T, rt http.RoundTripper) {
+	t.Helper()
+	previous := transport
+	SetTransport(rt)
+	t.Cleanup(func() { SetTransport(previous) })
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// Record a login and a user data request
+	ts, _ := newContractServer(t, http.StatusOK, "application/json", mustJSON(loginSuccess))
+	useTransport(t, NewRecorder(path, http.DefaultTransport))
+
+	recorded, err := Login(ts.URL, structs.LoginRequest{Email: "user@hbd.lotiguere.com", Password: "hunter2"}, 720)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if recorded.Token != loginSuccess.Token {
+		t.Fatalf("Login() token = %q, the recorder must not alter the response", recorded.Token)
+	}
+	if _, err := CheckBirthdays(ts.URL, "secret-token"); err != nil {
+		t.Fatalf("CheckBirthdays() error = %v", err)
+	}
+
+	// No secret makes it to the cassette
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading cassette: %v", err)
+	}
+	for _, secret := range []string{"hunter2", "secret-token", loginSuccess.Token, loginSuccess.TelegramBotAPIKey} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains the secret %q", secret)
+		}
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette() error = %v", err)
+	}
+	if len(cassette.Interactions) != 2 {
+		t.Fatalf("cassette has %d interactions, want 2", len(cassette.Interactions))
+	}
+	if auth := cassette.Interactions[1].Request.Header.Get("Authorization"); auth != "Bearer "+Redacted {
+		t.Errorf("recorded Authorization = %q, want it redacted", auth)
+	}
+
+	// Replay against a server that doesn't exist
+	ts.Close()
+	useTransport(t, NewReplayer(cassette))
+
+	replayed, err := Login(ts.URL, structs.LoginRequest{Email: "user@hbd.lotiguere.com", Password: "hunter2"}, 720)
+	if err != nil {
+		t.Fatalf("replayed Login() error = %v", err)
+	}
+	want := loginSuccess
+	want.Token = Redacted
+	want.TelegramBotAPIKey = Redacted
+	if !reflect.DeepEqual(*replayed, want) {
+		t.Errorf("replayed Login() = %+v, want %+v", *replayed, want)
+	}
+	if _, err := CheckBirthdays(ts.URL, "another-token"); err != nil {
+		t.Fatalf("replayed CheckBirthdays() error = %v", err)
+	}
+
+	// Every interaction is served once
+	_, err = CheckBirthdays(ts.URL, "another-token")
+	if !errors.Is(err, ErrNotRecorded) || IsNetworkError(err) {
+		t.Errorf("CheckBirthdays() past the cassette error = %v, want ErrNotRecorded", err)
+	}
+}
+
+func TestReplayNetworkError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	ts, _ := newContractServer(t, http.StatusOK, "", "")
+	ts.Close()
+	useTransport(t, NewRecorder(path, http.DefaultTransport))
+
+	if _, err := CheckHealth(ts.URL); !IsNetworkError(err) {
+		t.Fatalf("CheckHealth() error = %v, want a network error", err)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette() error = %v", err)
+	}
+	useTransport(t, NewReplayer(cassette))
+
+	if _, err := CheckHealth(ts.URL); !IsNetworkError(err) {
+		t.Errorf("replayed CheckHealth() error = %v, want a network error", err)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"email":"a@b.c","password":"pw"}`, `{"email":"a@b.c","password":"REDACTED"}`},
+		{`{"new_password":"","new_telegram_bot_api_key":"key"}`, `{"new_password":"","new_telegram_bot_api_key":"REDACTED"}`},
+		{`[{"token":"jwt"}]`, `[{"token":"REDACTED"}]`},
+		{`not json`, `not json`},
+	}
+
+	for _, tt := range tests {
+		if got := string(RedactBody([]byte(tt.body))); got != tt.want {
+			t.Errorf("RedactBody(%s) = %s, want %s", tt.body, got, tt.want)
+		}
+	}
+}
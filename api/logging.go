@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// LoggingTransport logs every request with its status and latency at the info level,
+// and the headers and bodies at the debug level. Secrets are redacted.
+type LoggingTransport struct {
+	logger *slog.Logger
+	next   http.RoundTripper
+}
+
+// NewLoggingTransport creates a transport logging to logger, sending the requests through next
+func NewLoggingTransport(logger *slog.Logger, next http.RoundTripper) *LoggingTransport {
+	return &LoggingTransport{logger: logger, next: next}
+}
+
+// RoundTrip sends the request and logs it along with its response
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	debug := t.logger.Enabled(ctx, slog.LevelDebug)
+
+	if debug {
+		body, err := readBody(&req.Body)
+		if err != nil {
+			return nil, err
+		}
+		t.logger.LogAttrs(ctx, slog.LevelDebug, "sending request",
+			slog.String("method", req.Method),
+			slog.String("url", req.URL.Redacted()),
+			slog.Any("header", RedactHeader(req.Header)),
+			slog.String("body", string(RedactBody(body))),
+		)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	latency := time.Since(start)
+
+	if err != nil {
+		t.logger.LogAttrs(ctx, slog.LevelWarn, "request failed",
+			slog.String("method", req.Method),
+			slog.String("url", req.URL.Redacted()),
+			slog.Duration("latency", latency),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	t.logger.LogAttrs(ctx, slog.LevelInfo, "request",
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.Redacted()),
+		slog.Int("status", resp.StatusCode),
+		slog.Duration("latency", latency),
+	)
+
+	if debug {
+		t.logResponse(ctx, resp)
+	}
+
+	return resp, nil
+}
+
+// logResponse logs the headers and body of a response, which can still be read afterwards
+func (t *LoggingTransport) logResponse(ctx context.Context, resp *http.Response) {
+	body, err := readBody(&resp.Body)
+	if err != nil {
+		t.logger.LogAttrs(ctx, slog.LevelDebug, "error reading response body", slog.String("error", err.Error()))
+		resp.Body = http.NoBody
+		return
+	}
+
+	t.logger.LogAttrs(ctx, slog.LevelDebug, "received response",
+		slog.Int("status", resp.StatusCode),
+		slog.Any("header", resp.Header),
+		slog.String("body", string(RedactBody(body))),
+	)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"bytes"
+	"hbd-cli/structs"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLoggingTransport(t *testing.T) {
+	ts, _ := newContractServer(t, http.StatusOK, "application/json", mustJSON(loginSuccess))
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	useTransport(t, NewLoggingTransport(logger, http.DefaultTransport))
+
+	resp, err := Login(ts.URL, structs.LoginRequest{Email: "user@hbd.lotiguere.com", Password: "hunter2"}, 720)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if resp.Token != loginSuccess.Token {
+		t.Fatalf("Login() token = %q, logging must not alter the response", resp.Token)
+	}
+	if _, err := CheckBirthdays(ts.URL, "secret-token"); err != nil {
+		t.Fatalf("CheckBirthdays() error = %v", err)
+	}
+
+	for _, want := range []string{"method=POST", "status=200", "latency=", "user@hbd.lotiguere.com", "Bearer " + Redacted} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs don't contain %q:\n%s", want, logs.String())
+		}
+	}
+	for _, secret := range []string{"hunter2", "secret-token", loginSuccess.Token, loginSuccess.TelegramBotAPIKey} {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("logs contain the secret %q", secret)
+		}
+	}
+}
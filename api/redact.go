@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces secrets in recorded and logged requests
+const Redacted = "REDACTED"
+
+// sensitiveFields are the JSON fields of requests and responses that hold secrets
+var sensitiveFields = map[string]bool{
+	"token":                    true,
+	"password":                 true,
+	"new_password":             true,
+	"telegram_bot_api_key":     true,
+	"new_telegram_bot_api_key": true,
+}
+
+// RedactHeader returns a copy of the headers with the bearer token replaced
+func RedactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	if auth := redacted.Get("Authorization"); auth != "" {
+		scheme, _, _ := strings.Cut(auth, " ")
+		redacted.Set("Authorization", scheme+" "+Redacted)
+	}
+	return redacted
+}
+
+// RedactBody returns a JSON body with the secret fields replaced, other bodies are returned as is
+func RedactBody(body []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body
+	}
+
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// redactValue replaces the secret fields of a decoded JSON value, at any depth
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if sensitiveFields[key] {
+				if s, ok := field.(string); ok && s != "" {
+					v[key] = Redacted
+				}
+				continue
+			}
+			v[key] = redactValue(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// SessionCache keeps the user data in memory across the commands of a session, like the ones run in
+// `hbd shell`, so it's only retrieved once. Any request other than a GET may change it, so they clear the cache.
+type SessionCache struct {
+	mu        sync.Mutex
+	responses map[string]*RecordedResponse
+}
+
+// NewSessionCache creates an empty session cache
+func NewSessionCache() *SessionCache {
+	return &SessionCache{responses: map[string]*RecordedResponse{}}
+}
+
+// Clear forgets every cached response, e.g. after logging in as someone else
+func (c *SessionCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.responses)
+}
+
+// Transport returns a transport answering the user data requests from the cache, sending the rest through next
+func (c *SessionCache) Transport(next http.RoundTripper) http.RoundTripper {
+	return &sessionTransport{cache: c, next: next}
+}
+
+type sessionTransport struct {
+	cache *SessionCache
+	next  http.RoundTripper
+}
+
+// RoundTrip answers the user data requests from the cache when possible, and caches their responses
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		t.cache.Clear()
+		return t.next.RoundTrip(req)
+	}
+	if !strings.HasSuffix(req.URL.Path, "/api/me") {
+		return t.next.RoundTrip(req)
+	}
+
+	// Each token has its own user data
+	key := req.URL.String() + " " + req.Header.Get("Authorization")
+
+	t.cache.mu.Lock()
+	cached, ok := t.cache.responses[key]
+	t.cache.mu.Unlock()
+	if ok {
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", cached.StatusCode, http.StatusText(cached.StatusCode)),
+			StatusCode:    cached.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        cached.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewBufferString(cached.Body)),
+			ContentLength: int64(len(cached.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	t.cache.mu.Lock()
+	t.cache.responses[key] = &RecordedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: string(body)}
+	t.cache.mu.Unlock()
+
+	return resp, nil
+}
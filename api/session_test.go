@@ -0,0 +1,58 @@
+package api
+
+import (
+	"hbd-cli/structs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionCache(t *testing.T) {
+	requests := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/me":
+			w.Write([]byte(`{"id":1,"birthdays":[{"id":1,"name":"John Doe","date":"1990-01-02"}]}`))
+		default:
+			w.Write([]byte(`{"id":2,"name":"Jane Doe","date":"1991-03-04"}`))
+		}
+	}))
+	defer ts.Close()
+
+	SetTransport(NewSessionCache().Transport(http.DefaultTransport))
+	defer SetTransport(http.DefaultTransport)
+
+	for i := 0; i < 2; i++ {
+		userData, err := GetUserData(ts.URL, "token")
+		if err != nil {
+			t.Fatalf("GetUserData() error = %v", err)
+		}
+		if len(userData.Birthdays) != 1 || userData.Birthdays[0].Name != "John Doe" {
+			t.Fatalf("GetUserData() birthdays = %+v, want John Doe", userData.Birthdays)
+		}
+	}
+	if got := requests["GET /api/me"]; got != 1 {
+		t.Errorf("user data requested %d times, want 1", got)
+	}
+
+	// Another token has its own user data
+	if _, err := GetUserData(ts.URL, "other-token"); err != nil {
+		t.Fatalf("GetUserData() error = %v", err)
+	}
+	if got := requests["GET /api/me"]; got != 2 {
+		t.Errorf("user data requested %d times, want 2", got)
+	}
+
+	// Changes clear the cache
+	if _, err := AddBirthday(ts.URL, "token", structs.BirthdayNameDateAdd{Name: "Jane Doe", Date: "1991-03-04"}); err != nil {
+		t.Fatalf("AddBirthday() error = %v", err)
+	}
+	if _, err := GetUserData(ts.URL, "token"); err != nil {
+		t.Fatalf("GetUserData() error = %v", err)
+	}
+	if got := requests["GET /api/me"]; got != 3 {
+		t.Errorf("user data requested %d times after a change, want 3", got)
+	}
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// NewUnixSocketTransport creates a transport sending every request through the Unix socket at path,
+// whatever the host of the request. It's based on base, without its proxy.
+func NewUnixSocketTransport(path string, base *http.Transport) *http.Transport {
+	transport := base.Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", path)
+	}
+
+	return transport
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixSocketTransport(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "hbd.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets not supported: %v", err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ready"}`))
+	}))
+	ts.Listener = listener
+	ts.Start()
+	defer ts.Close()
+
+	SetTransport(NewUnixSocketTransport(socket, http.DefaultTransport.(*http.Transport)))
+	defer SetTransport(http.DefaultTransport)
+
+	health, err := CheckHealth("http://localhost")
+	if err != nil {
+		t.Fatalf("CheckHealth() error = %v", err)
+	}
+	if health.Status != "ready" {
+		t.Errorf("CheckHealth() status = %q, want ready", health.Status)
+	}
+}
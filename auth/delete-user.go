@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"hbd-cli/api"
 	"hbd-cli/helper"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -31,40 +30,48 @@ Environment variables:
 Example usage:
   hbd-cli auth delete-user --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
-			// Load credentials
+			// Load the token
 			credsPath = filepath.Join(credsPath, host)
-			creds, err := helper.LoadCredentials(credsPath)
-			helper.HandleError("Error loading credentials from credentials file", err)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
 
 			// Ask for confirmation
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Println("Are you sure you want to delete your account? This action cannot be undone. Type 'yes' to proceed: ")
+			reader := bufio.NewReader(cmd.InOrStdin())
+			fmt.Fprintln(out, "Are you sure you want to delete your account? This action cannot be undone. Type 'yes' to proceed: ")
 			confirmation, _ := reader.ReadString('\n')
 			confirmation = strings.TrimSpace(confirmation)
 
 			if confirmation != "yes" {
-				fmt.Println("Account deletion aborted.")
-				return
+				fmt.Fprintln(out, "Account deletion aborted.")
+				return nil
 			}
 
 			// Create the URL
 			url := helper.GenUrl(host, port, ssl)
 
 			// Make the request
-			_, err = api.DeleteUser(url, creds.Token)
-			helper.HandleErrorExit("Error deleting user", err)
+			_, err = api.DeleteUser(url, token)
+			if err != nil {
+				return helper.NewError("Error deleting user", err)
+			}
 
 			// Delete the credentials file
 			if err := helper.DeleteCredentials(credsPath); err != nil {
-				helper.HandleErrorExit("Error deleting credentials file", err)
+				return helper.NewError("Error deleting credentials file", err)
 			}
 
 			// Print success message
-			fmt.Println("Account deleted successfully!")
+			fmt.Fprintln(out, "Account deleted successfully!")
+
+			return nil
 		},
 	}
 
@@ -25,16 +25,22 @@ Environment variables:
 Example usage:
   hbd-cli auth generate-password --host="hbd.lotiguere.com" --ssl
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Create the URL
 			url := helper.GenUrl(host, port, ssl)
 
 			// Make the request
 			password, err := api.GeneratePassword(url)
-			helper.HandleErrorExit("Error generating password", err)
+			if err != nil {
+				return helper.NewError("Error generating password", err)
+			}
 
 			// Print the generated password
-			fmt.Println(password.Password)
+			fmt.Fprintln(out, password.Password)
+
+			return nil
 		},
 	}
 
@@ -34,15 +34,16 @@ Environment variables:
 Example usage:
   hbd-cli auth login --email="user@hbd.lotiguere.com" --password="yourpassword" --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials" --token-duration=3600
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
 			// Load credentials
 			credsPath = filepath.Join(credsPath, host)
-			creds, err := helper.LoadCredentials(credsPath)
-			if err == nil {
-				fmt.Println("Credentials exist, they will be overwritten.")
+			if _, err := helper.LoadCredentials(credsPath); err == nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Credentials exist, they will be overwritten.")
 			}
 
 			// Check if email and password are an environment variable
@@ -56,7 +57,7 @@ Example usage:
 
 			// Check if email and password are empty
 			if email == "" || password == "" {
-				helper.HandleErrorExitStr("Error authenticating", "email and password must be provided either via flags or environment variables")
+				return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error authenticating", "email and password must be provided either via flags or environment variables"))
 			}
 
 			// Create the URL
@@ -70,16 +71,19 @@ Example usage:
 
 			// Make the request
 			loginSuccess, err := api.Login(url, loginReq, tokenDuration)
-			helper.HandleErrorExit("Error logging in, wrong email or password", err)
+			if err != nil {
+				return helper.NewError("Error logging in, wrong email or password", err)
+			}
 
 			// Save the token to the credentials file
-			creds.Token = loginSuccess.Token
-			if err := helper.SaveCredentials(credsPath, creds); err != nil {
-				helper.HandleErrorExit("Error saving credentials", err)
+			if err := helper.SaveCredentials(credsPath, &helper.Credentials{Token: loginSuccess.Token}); err != nil {
+				return helper.NewError("Error saving credentials", err)
 			}
 
 			// Print success message
-			fmt.Printf("Login successful! Token saved to %s\n", credsPath)
+			fmt.Fprintf(out, "Login successful! Token saved to %s\n", credsPath)
+
+			return nil
 		},
 	}
 
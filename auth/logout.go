@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"hbd-cli/helper"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -27,35 +26,41 @@ Environment variables:
 Example usage:
   hbd-cli auth logout --host="hbd.lotiguere.com" --creds-path="~/.hbd/credentials" -y
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
 			// Load credentials
 			credsPath = filepath.Join(credsPath, host)
 			_, err := helper.LoadCredentials(credsPath)
-			helper.HandleErrorExit("Error loading credentials from credentials file, are you sure you've logged in before?", err)
+			if err != nil {
+				return helper.NewError("Error loading credentials from credentials file, are you sure you've logged in before?", err)
+			}
 
 			// If autoConfirm is not set, ask for confirmation
 			if !autoConfirm {
-				reader := bufio.NewReader(os.Stdin)
-				fmt.Printf("Are you sure you want to log out? This will clear the credentials in %s. Type 'yes' to proceed: ", credsPath)
+				reader := bufio.NewReader(cmd.InOrStdin())
+				fmt.Fprintf(out, "Are you sure you want to log out? This will clear the credentials in %s. Type 'yes' to proceed: ", credsPath)
 				confirmation, _ := reader.ReadString('\n')
 				confirmation = strings.TrimSpace(confirmation)
 
 				if confirmation != "yes" {
-					fmt.Println("Logout cancelled.")
-					return
+					fmt.Fprintln(out, "Logout cancelled.")
+					return nil
 				}
 			}
 
 			// Delete the credentials file
 			if err := helper.DeleteCredentials(credsPath); err != nil {
-				helper.HandleErrorExit("Error deleting credentials file", err)
+				return helper.NewError("Error deleting credentials file", err)
 			}
 
 			// Print success message
-			fmt.Println("Logged out successfully.")
+			fmt.Fprintln(out, "Logged out successfully.")
+
+			return nil
 		},
 	}
 
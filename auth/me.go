@@ -2,17 +2,15 @@ package auth
 
 import (
 	"fmt"
-	"hbd-cli/api"
 	"hbd-cli/helper"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 func Me() *cobra.Command {
 	var host, port, credsPath string
-	var ssl, dotEnvFormat bool
+	var ssl, offline, dotEnvFormat bool
 
 	var meCmd = &cobra.Command{
 		Use:   "me",
@@ -24,58 +22,57 @@ Environment variables:
   HBD_HOST - The host for the service. Defaults to 0.0.0.0.
   HBD_PORT - The port for the service.
   HBD_SSL - Use SSL (https) for the connection.
+  HBD_OFFLINE - Serve the data from the local cache without contacting the server.
   HBD_CREDS_PATH - Path to the credentials file.
 
 Example usage:
   hbd-cli auth me --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials" --dotenv
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
-			// Load credentials
+			// Load the token, it's not needed for cached data
 			credsPath = filepath.Join(credsPath, host)
-			creds, err := helper.LoadCredentials(credsPath)
-			helper.HandleError("Error loading credentials from credentials file", err)
-
-			// Check if the token is provided via environment variable
-			token := creds.Token
-			if token == "" {
-				token = viper.GetString("HBD_TOKEN")
-			}
-
-			// Check if the token is still empty
-			if token == "" {
-				helper.HandleErrorExitStr("Error", "A valid JWT token must be provided either via the credentials file or the environment variable HBD_TOKEN")
+			token, err := helper.LoadToken(credsPath)
+			if err != nil && !offline {
+				return err
 			}
 
 			// Create the URL
 			url := helper.GenUrl(host, port, ssl)
 
-			// Make the request to get user data
-			userData, err := api.GetUserData(url, token)
-			helper.HandleErrorExit("Error retrieving user data", err)
+			// Make the request to get user data, or use the cache when offline
+			userData, fetchedAt, err := helper.FetchUserData(cmd.ErrOrStderr(), url, token, offline)
+			if err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
+			helper.PrintStaleNotice(cmd.ErrOrStderr(), fetchedAt)
 
 			// Print the retrieved user data
 			// In dotenv format
 			if dotEnvFormat {
-				fmt.Printf("HBD_USER_ID=%d\n", userData.ID)
-				fmt.Printf("HBD_TELEGRAM_BOT_API_KEY=%s\n", userData.TelegramBotAPIKey)
-				fmt.Printf("HBD_TELEGRAM_USER_ID=%s\n", userData.TelegramUserID)
-				fmt.Printf("HBD_REMINDER_TIME=%s\n", userData.ReminderTime)
-				fmt.Printf("HBD_TIMEZONE=%s\n\n", userData.Timezone)
-				fmt.Printf("# To view the birthdays use the 'birthdays' verb\n")
-				return
+				fmt.Fprintf(out, "HBD_USER_ID=%d\n", userData.ID)
+				fmt.Fprintf(out, "HBD_TELEGRAM_BOT_API_KEY=%s\n", userData.TelegramBotAPIKey)
+				fmt.Fprintf(out, "HBD_TELEGRAM_USER_ID=%s\n", userData.TelegramUserID)
+				fmt.Fprintf(out, "HBD_REMINDER_TIME=%s\n", userData.ReminderTime)
+				fmt.Fprintf(out, "HBD_TIMEZONE=%s\n\n", userData.Timezone)
+				fmt.Fprintf(out, "# To view the birthdays use the 'birthdays' verb\n")
+				return nil
 			}
 			
 			// In regular format
-			fmt.Printf("User Data:\n")
-			fmt.Printf("ID: %d\n", userData.ID)
-			fmt.Printf("Telegram Bot API Key: %s\n", userData.TelegramBotAPIKey)
-			fmt.Printf("Telegram User ID: %s\n", userData.TelegramUserID)
-			fmt.Printf("Reminder Time: %s\n", userData.ReminderTime)
-			fmt.Printf("Timezone: %s\n", userData.Timezone)
-			fmt.Printf("To view the birthdays use the 'birthdays' verb\n\n")
+			fmt.Fprintf(out, "User Data:\n")
+			fmt.Fprintf(out, "ID: %d\n", userData.ID)
+			fmt.Fprintf(out, "Telegram Bot API Key: %s\n", userData.TelegramBotAPIKey)
+			fmt.Fprintf(out, "Telegram User ID: %s\n", userData.TelegramUserID)
+			fmt.Fprintf(out, "Reminder Time: %s\n", userData.ReminderTime)
+			fmt.Fprintf(out, "Timezone: %s\n", userData.Timezone)
+			fmt.Fprintf(out, "To view the birthdays use the 'birthdays' verb\n\n")
+
+			return nil
 		},
 	}
 
@@ -83,6 +80,7 @@ Example usage:
 	meCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
 	meCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
 	meCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	meCmd.Flags().BoolVar(&offline, "offline", helper.DefaultOffline(), "Serve the data from the local cache without contacting the server")
 	meCmd.Flags().BoolVar(&dotEnvFormat, "dotenv", false, "Print the output in dotenv format (KEY=VALUE)")
 	meCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
 
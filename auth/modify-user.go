@@ -38,24 +38,17 @@ Environment variables:
 Example usage:
   hbd-cli auth modify-user --new-email="newuser@hbd.lotiguere.com" --new-password="newpassword" --new-reminder-time="15:04" --new-timezone="America/New_York" --new-telegram-bot-api-key="your-new-bot-api-key" --new-telegram-user-id="your-new-user-id"
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
-			// Load credentials
+			// Load the token
 			credsPath = filepath.Join(credsPath, host)
-			creds, err := helper.LoadCredentials(credsPath)
-			helper.HandleError("Error loading credentials from credentials file", err)
-
-			// Check if the token is provided via environment variable
-			token := creds.Token
-			if token == "" {
-				token = viper.GetString("HBD_TOKEN")
-			}
-
-			// Check if the token is still empty
-			if token == "" {
-				helper.HandleErrorExitStr("Error", "A valid JWT token must be provided either via the credentials file")
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
 			}
 
 			// Check if user details are provided via environment variables
@@ -83,7 +76,9 @@ Example usage:
 
 			// Get the user's existing data by calling the Me endpoint and fill up the missing fields with the existing data
 			userData, err := api.GetUserData(url, token)
-			helper.HandleErrorExit("Error retrieving user data", err)
+			if err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
 			if newReminderTime == "" {
 				newReminderTime = userData.ReminderTime
 			}
@@ -111,16 +106,17 @@ Example usage:
 
 				// Make the request to modify user details
 				success, err := api.ModifyUserWithEmail(url, token, modifyUserReq, tokenDuration)
-				helper.HandleErrorExit("Error modifying user details", err)
+				if err != nil {
+					return helper.NewError("Error modifying user details", err)
+				}
 
 				// Save the new token to the credentials file
-				creds.Token = success.Token
-				if err := helper.SaveCredentials(credsPath, creds); err != nil {
-					helper.HandleErrorExit("Error saving credentials", err)
+				if err := helper.SaveCredentials(credsPath, &helper.Credentials{Token: success.Token}); err != nil {
+					return helper.NewError("Error saving credentials", err)
 				}
 
 				// Print success message
-				fmt.Printf("User details modified successfully! Token saved to %s\n", credsPath)
+				fmt.Fprintf(out, "User details modified successfully! Token saved to %s\n", credsPath)
 
 				// If the user does not provide a new email OR password, call the ModifyUserWithoutEmail endpoint
 			} else if newEmail == "" && newPassword == "" {
@@ -135,19 +131,23 @@ Example usage:
 
 				// Make the request to modify user details
 				userData, err := api.ModifyUserWithoutEmail(url, token, modifyUserReq)
-				helper.HandleErrorExit("Error modifying user details", err)
+				if err != nil {
+					return helper.NewError("Error modifying user details", err)
+				}
 
 				// Print the modified user data
-				fmt.Printf("User data modified successfully!\n\n")
-				fmt.Printf("User Data:\n")
-				fmt.Printf("ID: %d\n", userData.ID)
-				fmt.Printf("Telegram Bot API Key: %s\n", userData.TelegramBotAPIKey)
-				fmt.Printf("Telegram User ID: %s\n", userData.TelegramUserID)
-				fmt.Printf("Reminder Time: %s\n", userData.ReminderTime)
-				fmt.Printf("Timezone: %s\n", userData.Timezone)
-				fmt.Printf("To view the birthdays use the 'birthdays' verb\n\n")
+				fmt.Fprintf(out, "User data modified successfully!\n\n")
+				fmt.Fprintf(out, "User Data:\n")
+				fmt.Fprintf(out, "ID: %d\n", userData.ID)
+				fmt.Fprintf(out, "Telegram Bot API Key: %s\n", userData.TelegramBotAPIKey)
+				fmt.Fprintf(out, "Telegram User ID: %s\n", userData.TelegramUserID)
+				fmt.Fprintf(out, "Reminder Time: %s\n", userData.ReminderTime)
+				fmt.Fprintf(out, "Timezone: %s\n", userData.Timezone)
+				fmt.Fprintf(out, "To view the birthdays use the 'birthdays' verb\n\n")
 
 			}
+
+			return nil
 		},
 	}
 
@@ -164,6 +164,10 @@ Example usage:
 	modifyUserCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
 	modifyUserCmd.Flags().IntVar(&tokenDuration, "token-duration", 720, "Duration of the JWT token in hours. Default is 720 hours (30 days), in case of reauth due to modified email or password.")
 
+	// Complete the reminder time and timezone
+	modifyUserCmd.RegisterFlagCompletionFunc("new-reminder-time", helper.CompleteReminderTime)
+	modifyUserCmd.RegisterFlagCompletionFunc("new-timezone", helper.CompleteTimezones)
+
 	// Return the modify-user command
 	return modifyUserCmd
 }
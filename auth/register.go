@@ -38,14 +38,14 @@ Environment variables:
 Example usage:
   hbd-cli auth register --email="user@hbd.lotiguere.com" --password="yourpassword" --reminder-time="15:04" --timezone="America/New_York" --telegram-bot-api-key="your-bot-api-key" --telegram-user-id="your-user-id"
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
-			// Load credentials
+			// The credentials are saved per host
 			credsPath = filepath.Join(credsPath, host)
-			creds, err := helper.LoadCredentials(credsPath)
-			helper.HandleError("Error loading credentials from credentials file", err)
 
 			// Check if user details are provided via environment variables
 			if email == "" {
@@ -72,7 +72,7 @@ Example usage:
 				// Print the missing details
 				for i, detail := range []string{email, password, reminderTime, timezone, telegramBotAPIKey, telegramUserID} {
 					if detail == "" {
-						fmt.Printf("Missing detail: %s\n", []string{
+						fmt.Fprintf(cmd.ErrOrStderr(), "Missing detail: %s\n", []string{
 							"email",
 							"password",
 							"reminder-time",
@@ -83,7 +83,7 @@ Example usage:
 					}
 				}
 
-				helper.HandleErrorExitStr("Error registering", "All registration details must be provided either via flags or environment variables")
+				return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error registering", "All registration details must be provided either via flags or environment variables"))
 			}
 
 			// Create the URL
@@ -101,16 +101,19 @@ Example usage:
 
 			// Make the registration request
 			loginSuccess, err := api.Register(url, registerReq, tokenDuration)
-			helper.HandleErrorExit("Error registering user", err)
+			if err != nil {
+				return helper.NewError("Error registering user", err)
+			}
 
 			// Save the token to the credentials file
-			creds.Token = loginSuccess.Token
-			if err := helper.SaveCredentials(credsPath, creds); err != nil {
-				helper.HandleErrorExit("Error saving credentials", err)
+			if err := helper.SaveCredentials(credsPath, &helper.Credentials{Token: loginSuccess.Token}); err != nil {
+				return helper.NewError("Error saving credentials", err)
 			}
 
 			// Print success message
-			fmt.Printf("Registration successful! Token saved to %s\n", credsPath)
+			fmt.Fprintf(out, "Registration successful! Token saved to %s\n", credsPath)
+
+			return nil
 		},
 	}
 
@@ -127,6 +130,10 @@ Example usage:
 	registerCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
 	registerCmd.Flags().IntVar(&tokenDuration, "token-duration", 720, "Duration of the JWT token in hours. Default is 720 hours (30 days).")
 
+	// Complete the reminder time and timezone
+	registerCmd.RegisterFlagCompletionFunc("reminder-time", helper.CompleteReminderTime)
+	registerCmd.RegisterFlagCompletionFunc("timezone", helper.CompleteTimezones)
+
 	// Return the register command
 	return registerCmd
 }
@@ -1,11 +1,14 @@
 package birthdays
 
 import (
+	"bufio"
 	"fmt"
 	"hbd-cli/api"
 	"hbd-cli/helper"
 	"hbd-cli/structs"
+	"io"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -20,53 +23,138 @@ func AddBirthday() *cobra.Command {
 		Short: "Add a new birthday",
 		Long:  `The add-birthday command allows you to add a new birthday to your account.
 
+When run from a terminal without --name or --date, an interactive wizard asks
+for the missing values, validates them and lets you add several birthdays in a row.
+
 Environment variables:
   HBD_CREDS_PATH - Path to the credentials file.
   HBD_HOST - The host for the service. Defaults to 0.0.0.0.
-  HBD_PORT - The port for the service. 
+  HBD_PORT - The port for the service.
   HBD_SSL - Use SSL (https) for the connection.
 
 Example usage:
   hbd-cli birthdays add --name="John Doe" --date="2021-12-25" --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
-			// Load credentials
+			// Fail fast when required values are missing and there's no one to ask
+			interactive := name == "" || date == ""
+			if interactive && !helper.IsInteractive(cmd.InOrStdin()) {
+				var missing []string
+				if name == "" {
+					missing = append(missing, `"name"`)
+				}
+				if date == "" {
+					missing = append(missing, `"date"`)
+				}
+				return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error adding birthday", fmt.Sprintf("required flag(s) %s not set", strings.Join(missing, ", "))))
+			}
+
+			// Validate the values given as flags
+			if name != "" {
+				if err := helper.ValidateBirthdayName(name); err != nil {
+					return helper.WithExitCode(helper.ExitValidation, helper.NewError("Error adding birthday", err))
+				}
+			}
+			if date != "" {
+				if err := helper.ValidateBirthdayDate(date); err != nil {
+					return helper.WithExitCode(helper.ExitValidation, helper.NewError("Error adding birthday", err))
+				}
+			}
+
+			// Load the token
 			credsPath = filepath.Join(credsPath, host)
-			creds, err := helper.LoadCredentials(credsPath)
-			helper.HandleError("Error loading credentials from credentials file", err)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
 
 			// Create the URL
 			url := helper.GenUrl(host, port, ssl)
 
-			// Create the JSON payload
-			birthdayReq := structs.BirthdayNameDateAdd{
-				Name: name,
-				Date: date,
+			// Non-interactive mode, add the birthday given as flags
+			if !interactive {
+				return addBirthday(out, cmd.ErrOrStderr(), url, token, name, date)
 			}
 
-			// Make the request
-			_, err = api.AddBirthday(url, creds.Token, birthdayReq)
-			helper.HandleErrorExit("Error adding birthday", err)
+			// Interactive mode, ask for the missing values until the user is done
+			reader := bufio.NewReader(cmd.InOrStdin())
+			for {
+				name, err = helper.Prompt(reader, out, "Name", name, helper.ValidateBirthdayName)
+				if err != nil {
+					return helper.NewError("Error adding birthday", err)
+				}
+
+				date, err = helper.Prompt(reader, out, "Date (YYYY-MM-DD)", date, helper.ValidateBirthdayDate)
+				if err != nil {
+					return helper.NewError("Error adding birthday", err)
+				}
 
-			// Print success message
-			fmt.Printf("Birthday for %s on %s added successfully!\n", name, date)
+				// Show a summary and ask for confirmation
+				fmt.Fprintf(out, "\nAbout to add:\n  Name: %s\n  Date: %s\n", name, date)
+				if helper.Confirm(reader, out, "Add this birthday?", true) {
+					if err := addBirthday(out, cmd.ErrOrStderr(), url, token, name, date); err != nil {
+						return err
+					}
+				} else {
+					fmt.Fprintln(out, "Birthday not added.")
+				}
+
+				// Offer to add another one, starting from scratch
+				if !helper.Confirm(reader, out, "Add another birthday?", false) {
+					return nil
+				}
+				name, date = "", ""
+				fmt.Fprintln(out)
+			}
 		},
 	}
 
 	// Add flags
-	addBirthdayCmd.Flags().StringVar(&name, "name", "", "Name of the person (required)")
-	addBirthdayCmd.Flags().StringVar(&date, "date", "", "Date of the birthday (required, format YYYY-MM-DD)")
+	addBirthdayCmd.Flags().StringVar(&name, "name", "", "Name of the person (required, prompted for when interactive)")
+	addBirthdayCmd.Flags().StringVar(&date, "date", "", "Date of the birthday (required, prompted for when interactive, format YYYY-MM-DD)")
 	addBirthdayCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
 	addBirthdayCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
 	addBirthdayCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
 	addBirthdayCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
 
-	// Mark required flags
-	addBirthdayCmd.MarkFlagRequired("name")
-	addBirthdayCmd.MarkFlagRequired("date")
-
 	return addBirthdayCmd
 }
+
+// addBirthday adds a single birthday, records it in the journal and prints the result to out,
+// the birthday is queued instead when the server is unreachable. Warnings go to errOut.
+func addBirthday(out, errOut io.Writer, url, token, name, date string) error {
+	// Create the JSON payload
+	birthdayReq := structs.BirthdayNameDateAdd{
+		Name: name,
+		Date: date,
+	}
+
+	// Make the request
+	birthday, err := api.AddBirthday(url, token, birthdayReq)
+
+	// Queue the change when the server can't be reached
+	if api.IsNetworkError(err) {
+		err = helper.QueueBirthdayChange(url, helper.JournalAdd, nil, &structs.BirthdayFull{Name: name, Date: date})
+		if err != nil {
+			return helper.NewError("Error queueing birthday", err)
+		}
+		fmt.Fprintf(out, "Server unreachable, birthday for %s on %s queued. Run 'hbd sync' once back online.\n", name, date)
+		return nil
+	}
+	if err != nil {
+		return helper.NewError("Error adding birthday", err)
+	}
+
+	// Record the change so it can be undone
+	helper.RecordBirthdayChange(errOut, url, helper.JournalAdd, nil, birthday)
+
+	// Print success message
+	fmt.Fprintf(out, "Birthday for %s on %s added successfully!\n", name, date)
+
+	return nil
+}
@@ -28,24 +28,32 @@ Environment variables:
 Example usage:
   hbd-cli birthdays check --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
 `,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
-			// Load credentials
+			// Load the token
 			credsPath = filepath.Join(credsPath, host)
-			creds, err := helper.LoadCredentials(credsPath)
-			helper.HandleError("Error loading credentials from credentials file", err)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
 
 			// Create the URL
 			url := helper.GenUrl(host, port, ssl)
 
 			// Make the request to check birthdays
-			_, err = api.CheckBirthdays(url, creds.Token)
-			helper.HandleErrorExit("Error checking birthdays", err)
+			_, err = api.CheckBirthdays(url, token)
+			if err != nil {
+				return helper.NewError("Error checking birthdays", err)
+			}
 
 			// Print the result
-			fmt.Printf("Check performed, if there's a birthday today, you should receive a message.")
+			fmt.Fprintf(out, "Check performed, if there's a birthday today, you should receive a message.")
+
+			return nil
 		},
 	}
 
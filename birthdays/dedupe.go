@@ -0,0 +1,296 @@
+package birthdays
+
+import (
+	"bufio"
+	"fmt"
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Strategies to pick the entry to keep in each cluster of duplicates
+const (
+	strategyKeepLowestID  = "keep-lowest-id"
+	strategyKeepHighestID = "keep-highest-id"
+)
+
+// DedupeBirthdays command
+func DedupeBirthdays() *cobra.Command {
+	var strategy, host, port, credsPath string
+	var threshold float64
+	var ssl, dryRun bool
+
+	var dedupeBirthdaysCmd = &cobra.Command{
+		Use:   "dedupe",
+		Short: "Find and merge duplicate birthdays",
+		Long: `The dedupe command finds birthdays that are likely duplicates and lets you keep
+one entry of each cluster, deleting the rest.
+
+Two birthdays are considered duplicates when they fall on the same date and their
+normalized names (case, accents, spacing and punctuation are ignored) are at least as
+similar as the threshold, where 1 only groups names that are identical once normalized.
+
+Without --strategy you are asked which entry to keep for each cluster.
+
+Environment variables:
+  HBD_CREDS_PATH - Path to the credentials file.
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service.
+  HBD_SSL - Use SSL (https) for the connection.
+
+Example usage:
+  hbd-cli birthdays dedupe --threshold=0.8 --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
+  hbd-cli birthdays dedupe --strategy=keep-lowest-id
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+
+			// Validate the flags
+			if threshold <= 0 || threshold > 1 {
+				return helper.WithExitCode(helper.ExitValidation, helper.NewErrorStr("Error validating flags", "threshold must be greater than 0 and at most 1"))
+			}
+			switch strategy {
+			case "", strategyKeepLowestID, strategyKeepHighestID:
+			default:
+				return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error validating flags", fmt.Sprintf("unknown strategy %q, must be %q or %q", strategy, strategyKeepLowestID, strategyKeepHighestID)))
+			}
+			if strategy == "" && !dryRun && !helper.IsInteractive(cmd.InOrStdin()) {
+				return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error validating flags", "--strategy or --dry-run is required when not running in a terminal"))
+			}
+
+			// Load the token
+			credsPath = filepath.Join(credsPath, host)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
+
+			// Create the URL
+			url := helper.GenUrl(host, port, ssl)
+
+			// Make the request to get user data
+			userData, err := api.GetUserData(url, token)
+			if err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
+
+			// Group the duplicates
+			clusters := findDuplicates(userData.Birthdays, threshold)
+			if len(clusters) == 0 {
+				fmt.Fprintln(out, "No duplicate birthdays found.")
+				return nil
+			}
+
+			// Decide which entries to delete in each cluster
+			reader := bufio.NewReader(cmd.InOrStdin())
+			var toDelete []structs.BirthdayFull
+			for i, cluster := range clusters {
+				fmt.Fprintf(out, "\nCluster %d of %d (%s):\n", i+1, len(clusters), cluster[0].Date)
+				for j, birthday := range cluster {
+					fmt.Fprintf(out, "  [%d] ID: %d, Name: %s\n", j+1, birthday.ID, birthday.Name)
+				}
+
+				if dryRun {
+					continue
+				}
+
+				keep := 0
+				switch strategy {
+				case strategyKeepLowestID:
+					keep = 0
+				case strategyKeepHighestID:
+					keep = len(cluster) - 1
+				default:
+					answer, err := helper.Prompt(reader, out, "Entry to keep (s to skip)", "1", func(answer string) error {
+						if answer == "s" {
+							return nil
+						}
+						n, err := strconv.Atoi(answer)
+						if err != nil || n < 1 || n > len(cluster) {
+							return fmt.Errorf("answer must be between 1 and %d", len(cluster))
+						}
+						return nil
+					})
+					if err != nil {
+						return helper.NewError("Error reading answer", err)
+					}
+
+					if answer == "s" {
+						continue
+					}
+					keep, _ = strconv.Atoi(answer)
+					keep--
+				}
+
+				fmt.Fprintf(out, "  Keeping ID %d\n", cluster[keep].ID)
+				for j, birthday := range cluster {
+					if j != keep {
+						toDelete = append(toDelete, birthday)
+					}
+				}
+			}
+
+			if dryRun || len(toDelete) == 0 {
+				return nil
+			}
+
+			// Delete the duplicates
+			fmt.Fprintln(out)
+			errs := forEachConcurrently(toDelete, 4, func(birthday structs.BirthdayFull) error {
+				_, err := api.DeleteBirthday(url, token, structs.BirthdayNameDateModify{ID: birthday.ID})
+				return err
+			})
+
+			// Print the report
+			failed := 0
+			for i, birthday := range toDelete {
+				if errs[i] != nil {
+					failed++
+					fmt.Fprintf(out, "FAILED  ID: %d, Name: %s, Date: %s (%v)\n", birthday.ID, birthday.Name, birthday.Date, errs[i])
+					continue
+				}
+				fmt.Fprintf(out, "DELETED ID: %d, Name: %s, Date: %s\n", birthday.ID, birthday.Name, birthday.Date)
+
+				// Record the change so it can be undone
+				helper.RecordBirthdayChange(cmd.ErrOrStderr(), url, helper.JournalDelete, &birthday, nil)
+			}
+			fmt.Fprintf(out, "%d of %d duplicate(s) deleted successfully.\n", len(toDelete)-failed, len(toDelete))
+
+			if failed > 0 {
+				return helper.NewErrorStr("Error deleting duplicates", fmt.Sprintf("%d deletion(s) failed", failed))
+			}
+
+			return nil
+		},
+	}
+
+	// Add flags
+	dedupeBirthdaysCmd.Flags().Float64Var(&threshold, "threshold", 0.85, "Minimum name similarity (0-1] for two birthdays on the same date to be duplicates")
+	dedupeBirthdaysCmd.Flags().StringVar(&strategy, "strategy", "", "Pick the entry to keep without asking (keep-lowest-id or keep-highest-id)")
+	dedupeBirthdaysCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the duplicates, don't delete anything")
+	dedupeBirthdaysCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	dedupeBirthdaysCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
+	dedupeBirthdaysCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	dedupeBirthdaysCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
+
+	return dedupeBirthdaysCmd
+}
+
+// findDuplicates groups birthdays on the same date with similar names,
+// clusters and their entries are sorted by ID
+func findDuplicates(birthdays []structs.BirthdayFull, threshold float64) [][]structs.BirthdayFull {
+	// Group by date first, duplicates never span dates
+	byDate := map[string][]structs.BirthdayFull{}
+	for _, birthday := range birthdays {
+		date := birthday.Date
+		if parsed, err := helper.ParseBirthdayDate(date); err == nil {
+			date = parsed.Format(helper.DateLayout)
+		}
+		byDate[date] = append(byDate[date], birthday)
+	}
+
+	var clusters [][]structs.BirthdayFull
+	for _, group := range byDate {
+		if len(group) < 2 {
+			continue
+		}
+
+		// Union similar names, transitively
+		parent := make([]int, len(group))
+		for i := range parent {
+			parent[i] = i
+		}
+		var find func(int) int
+		find = func(i int) int {
+			if parent[i] != i {
+				parent[i] = find(parent[i])
+			}
+			return parent[i]
+		}
+
+		names := make([]string, len(group))
+		for i, birthday := range group {
+			names[i] = normalizeName(birthday.Name)
+		}
+		for i := range group {
+			for j := i + 1; j < len(group); j++ {
+				if nameSimilarity(names[i], names[j]) >= threshold {
+					parent[find(i)] = find(j)
+				}
+			}
+		}
+
+		// Collect the sets with more than one entry
+		sets := map[int][]structs.BirthdayFull{}
+		for i, birthday := range group {
+			root := find(i)
+			sets[root] = append(sets[root], birthday)
+		}
+		for _, set := range sets {
+			if len(set) < 2 {
+				continue
+			}
+			sort.Slice(set, func(a, b int) bool { return set[a].ID < set[b].ID })
+			clusters = append(clusters, set)
+		}
+	}
+
+	sort.Slice(clusters, func(a, b int) bool { return clusters[a][0].ID < clusters[b][0].ID })
+	return clusters
+}
+
+// normalizeName lowercases a name and drops accents, punctuation and repeated spaces
+func normalizeName(name string) string {
+	var b strings.Builder
+	// Decomposed letters are followed by their accents as separate marks
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_' || r == '.':
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// nameSimilarity returns 1 minus the edit distance between the names relative to the longest one
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	// Levenshtein distance, keeping only the previous row
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
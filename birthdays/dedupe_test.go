@@ -0,0 +1,111 @@
+package birthdays
+
+import (
+	"hbd-cli/structs"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"lowercase", "John Doe", "john doe"},
+		{"repeated spaces", "  john   doe ", "john doe"},
+		{"separators", "Jean-Luc_Picard.Jr", "jean luc picard jr"},
+		{"punctuation", "O'Brien, Miles!", "obrien miles"},
+		{"accents", "José Müller", "jose muller"},
+		{"decomposed accents", "Ine\u0301s Zoe\u0308", "ines zoe"},
+		{"non latin letters", "Мария Иванова", "мария иванова"},
+		{"digits", "John Doe 2", "john doe 2"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeName(tt.in); got != tt.want {
+				t.Errorf("normalizeName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"identical", "john doe", "john doe", 1},
+		{"both empty", "", "", 1},
+		{"one empty", "john", "", 0},
+		{"one substitution", "john doe", "jon doe", 7.0 / 8},
+		{"one insertion", "john doe", "john does", 8.0 / 9},
+		{"transposition", "john doe", "jhon doe", 6.0 / 8},
+		{"different", "john", "mary", 0},
+		{"runes not bytes", "maría", "marta", 4.0 / 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nameSimilarity(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if reverse := nameSimilarity(tt.b, tt.a); math.Abs(reverse-got) > 1e-9 {
+				t.Errorf("nameSimilarity(%q, %q) = %v, not symmetric with %v", tt.b, tt.a, reverse, got)
+			}
+		})
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	birthdays := []structs.BirthdayFull{
+		{ID: 1, Name: "John Doe", Date: "1990-01-01"},
+		{ID: 2, Name: "john  doe", Date: "1990-01-01"},
+		{ID: 3, Name: "Jon Doe", Date: "1990-01-01"},
+		{ID: 4, Name: "John Doe", Date: "1991-01-01"},
+		{ID: 5, Name: "José Müller", Date: "1985-06-15"},
+		{ID: 6, Name: "JOSE MULLER", Date: "1985-06-15T00:00:00Z"},
+		{ID: 7, Name: "Jane Roe", Date: "1990-01-01"},
+		{ID: 8, Name: "Jon Do", Date: "1990-01-01"},
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		want      [][]int64
+	}{
+		// Only names that are identical once normalized, on the same date whatever its format
+		{"exact", 1, [][]int64{{1, 2}, {5, 6}}},
+		// "Jon Doe" is 7/8 similar to "John Doe", "Jon Do" 6/7 to "Jon Doe" and joins transitively
+		{"default", 0.85, [][]int64{{1, 2, 3, 8}, {5, 6}}},
+		// "Jon Do" is only 6/8 similar to "John Doe" and 6/7 to "Jon Doe"
+		{"strict", 0.86, [][]int64{{1, 2, 3}, {5, 6}}},
+		// A low threshold also groups different names, but never across dates
+		{"loose", 0.3, [][]int64{{1, 2, 3, 7, 8}, {5, 6}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]int64
+			for _, cluster := range findDuplicates(birthdays, tt.threshold) {
+				var ids []int64
+				for _, birthday := range cluster {
+					ids = append(ids, birthday.ID)
+				}
+				got = append(got, ids)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findDuplicates(threshold %v) = %v, want %v", tt.threshold, got, tt.want)
+			}
+		})
+	}
+
+	if got := findDuplicates(birthdays[:1], 0.85); got != nil {
+		t.Errorf("findDuplicates() of a single birthday = %v, want none", got)
+	}
+}
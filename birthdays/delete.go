@@ -1,70 +1,175 @@
 package birthdays
 
 import (
+	"bufio"
 	"fmt"
 	"hbd-cli/api"
 	"hbd-cli/helper"
 	"hbd-cli/structs"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 // DeleteBirthday command
 func DeleteBirthday() *cobra.Command {
-	var id int64
-	var host, port, credsPath string
-	var ssl bool
+	var ids []string
+	var match, host, port, credsPath string
+	var month, concurrency int
+	var ssl, autoConfirm bool
 
 	var deleteBirthdayCmd = &cobra.Command{
-		Use:   "delete",
-		Short: "Delete a birthday",
-		Long:  `The delete-birthday command allows you to delete an existing birthday by providing its ID.
-		
+		Use:   "delete [ID or ID range...]",
+		Short: "Delete one or more birthdays",
+		Long:  `The delete-birthday command allows you to delete existing birthdays.
+
+Birthdays can be selected by ID, by ID range (e.g. 5-9), by a case-insensitive
+name expression (--match) and by birth month (--month). When several criteria are
+given, only the birthdays matching all of them are deleted. Nothing is deleted when an ID
+given on its own doesn't exist, IDs in a range without a birthday are skipped.
+
+A preview of the birthdays to delete is shown before asking for confirmation,
+use --yes to skip it. The command exits with a non-zero status if any deletion fails.
+
 Environment variables:
   HBD_CREDS_PATH - Path to the credentials file.
   HBD_HOST - The host for the service. Defaults to 0.0.0.0.
-  HBD_PORT - The port for the service. 
+  HBD_PORT - The port for the service.
   HBD_SSL - Use SSL (https) for the connection.
 
 Example usage:
   hbd-cli birthdays delete --id=1 --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
+  hbd-cli birthdays delete 3 5-9 --yes
+  hbd-cli birthdays delete --match="^john" --month=12 --concurrency=8
 	`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
-			// Load credentials
+			// Parse the IDs given as flags or arguments
+			parsedIDs, err := parseIDs(append(ids, args...))
+			if err != nil {
+				return helper.WithExitCode(selectionExitCode(err), helper.NewError("Error parsing IDs", err))
+			}
+
+			sel := selector{IDs: parsedIDs, Match: match, Month: month}
+			if sel.empty() {
+				return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error deleting birthdays", "at least one ID, --match or --month must be provided"))
+			}
+			_, err = sel.compile()
+			if err != nil {
+				return helper.WithExitCode(selectionExitCode(err), helper.NewError("Error parsing filters", err))
+			}
+
+			// Load the token
 			credsPath = filepath.Join(credsPath, host)
-			creds, err := helper.LoadCredentials(credsPath)
-			helper.HandleError("Error loading credentials from credentials file", err)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
 
 			// Create the URL
 			url := helper.GenUrl(host, port, ssl)
 
-			// Create the JSON payload
-			birthdayReq := structs.BirthdayNameDateModify{
-				ID: id,
+			// Get the current birthdays to resolve the selection,
+			// the cached ones are used when the server is unreachable
+			userData, fetchedAt, err := helper.FetchUserData(cmd.ErrOrStderr(), url, token, false)
+			if err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
+			helper.PrintStaleNotice(cmd.ErrOrStderr(), fetchedAt)
+			offline := !fetchedAt.IsZero()
+
+			selected, err := resolveSelection(sel, userData.Birthdays, "Error deleting birthdays")
+			if err != nil {
+				return err
+			}
+
+			// Print a preview of what will be deleted
+			if offline {
+				fmt.Fprintln(out, "The server is unreachable, the deletions will be queued until 'hbd sync' is run.")
+			}
+			fmt.Fprintf(out, "The following %d birthday(s) will be deleted:\n", len(selected))
+			for _, birthday := range selected {
+				fmt.Fprintf(out, "ID: %d, Name: %s, Date: %s\n", birthday.ID, birthday.Name, birthday.Date)
+			}
+
+			// If autoConfirm is not set, ask for confirmation
+			if !autoConfirm {
+				reader := bufio.NewReader(cmd.InOrStdin())
+				fmt.Fprint(out, "Are you sure you want to delete these birthdays? This action cannot be undone. Type 'yes' to proceed: ")
+				confirmation, _ := reader.ReadString('\n')
+				confirmation = strings.TrimSpace(confirmation)
+
+				if confirmation != "yes" {
+					fmt.Fprintln(out, "Deletion cancelled.")
+					return nil
+				}
+			}
+
+			// Delete the birthdays with bounded concurrency, unless the server is already known to be unreachable
+			errs := make([]error, len(selected))
+			if !offline {
+				errs = forEachConcurrently(selected, concurrency, func(birthday structs.BirthdayFull) error {
+					_, err := api.DeleteBirthday(url, token, structs.BirthdayNameDateModify{ID: birthday.ID})
+					return err
+				})
 			}
 
-			// Make the request
-			_, err = api.DeleteBirthday(url, creds.Token, birthdayReq)
-			helper.HandleErrorExit("Error deleting birthday", err)
+			// Print the report
+			failed, queued := 0, 0
+			for i, birthday := range selected {
+				// Queue the deletions that couldn't reach the server
+				if offline || api.IsNetworkError(errs[i]) {
+					errs[i] = helper.QueueBirthdayChange(url, helper.JournalDelete, &birthday, nil)
+					if errs[i] == nil {
+						queued++
+						fmt.Fprintf(out, "QUEUED  ID: %d, Name: %s, Date: %s\n", birthday.ID, birthday.Name, birthday.Date)
+						continue
+					}
+				}
+
+				if errs[i] != nil {
+					failed++
+					fmt.Fprintf(out, "FAILED  ID: %d, Name: %s, Date: %s (%v)\n", birthday.ID, birthday.Name, birthday.Date, errs[i])
+					continue
+				}
+				fmt.Fprintf(out, "DELETED ID: %d, Name: %s, Date: %s\n", birthday.ID, birthday.Name, birthday.Date)
+
+				// Record the change so it can be undone
+				helper.RecordBirthdayChange(cmd.ErrOrStderr(), url, helper.JournalDelete, &birthday, nil)
+			}
+			fmt.Fprintf(out, "%d of %d birthday(s) deleted successfully.\n", len(selected)-failed-queued, len(selected))
+			if queued > 0 {
+				fmt.Fprintf(out, "%d deletion(s) queued, run 'hbd sync' once back online.\n", queued)
+			}
+
+			if failed > 0 {
+				return helper.NewErrorStr("Error deleting birthdays", fmt.Sprintf("%d deletion(s) failed", failed))
+			}
 
-			// Print success message
-			fmt.Printf("Birthday with ID %d deleted successfully!\n", id)
+			return nil
 		},
 	}
 
 	// Add flags
-	deleteBirthdayCmd.Flags().Int64Var(&id, "id", 0, "ID of the birthday to delete (required)")
+	deleteBirthdayCmd.Flags().StringSliceVar(&ids, "id", nil, "ID(s) or ID range(s) of the birthdays to delete, e.g. 1,3,5-9")
+	deleteBirthdayCmd.Flags().StringVar(&match, "match", "", "Delete birthdays whose name matches this case-insensitive expression")
+	deleteBirthdayCmd.Flags().IntVar(&month, "month", 0, "Delete birthdays in this month (1-12)")
+	deleteBirthdayCmd.Flags().BoolVarP(&autoConfirm, "yes", "y", false, "Automatic yes to confirmation prompt")
+	deleteBirthdayCmd.Flags().IntVar(&concurrency, "concurrency", 4, "Maximum number of deletions running at the same time")
 	deleteBirthdayCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
 	deleteBirthdayCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
 	deleteBirthdayCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
 	deleteBirthdayCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
 
-	// Mark required flags
-	deleteBirthdayCmd.MarkFlagRequired("id")
+	// Complete the IDs and names of the birthdays
+	deleteBirthdayCmd.ValidArgsFunction = helper.CompleteBirthdayIDs
+	deleteBirthdayCmd.RegisterFlagCompletionFunc("id", helper.CompleteBirthdayIDs)
+	deleteBirthdayCmd.RegisterFlagCompletionFunc("match", helper.CompleteBirthdayNames)
 
 	return deleteBirthdayCmd
 }
@@ -2,7 +2,6 @@ package birthdays
 
 import (
 	"fmt"
-	"hbd-cli/api"
 	"hbd-cli/helper"
 	"path/filepath"
 
@@ -12,7 +11,7 @@ import (
 // ListBirthdays command
 func ListBirthdays() *cobra.Command {
 	var host, port, credsPath string
-	var ssl bool
+	var ssl, offline bool
 
 	var listBirthdaysCmd = &cobra.Command{
 		Use:   "list",
@@ -24,31 +23,41 @@ Environment variables:
   HBD_HOST - The host for the service. Defaults to
   HBD_PORT - The port for the service.
   HBD_SSL - Use SSL (https) for the connection.
+  HBD_OFFLINE - Serve the data from the local cache without contacting the server.
 
 Example usage:
   hbd-cli birthdays list --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
-			// Load credentials
+			// Load the token, it's not needed for cached data
 			credsPath = filepath.Join(credsPath, host)
-			creds, err := helper.LoadCredentials(credsPath)
-			helper.HandleError("Error loading credentials from credentials file", err)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil && !offline {
+				return err
+			}
 
 			// Create the URL
 			url := helper.GenUrl(host, port, ssl)
 
-			// Make the request to get user data
-			userData, err := api.GetUserData(url, creds.Token)
-			helper.HandleErrorExit("Error retrieving user data", err)
+			// Make the request to get user data, or use the cache when offline
+			userData, fetchedAt, err := helper.FetchUserData(cmd.ErrOrStderr(), url, token, offline)
+			if err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
+			helper.PrintStaleNotice(cmd.ErrOrStderr(), fetchedAt)
 
 			// Print the birthdays
-			fmt.Println("Your Birthdays:")
+			fmt.Fprintln(out, "Your Birthdays:")
 			for _, birthday := range userData.Birthdays {
-				fmt.Printf("ID: %d, Name: %s, Date: %s\n", birthday.ID, birthday.Name, birthday.Date)
+				fmt.Fprintf(out, "ID: %d, Name: %s, Date: %s\n", birthday.ID, birthday.Name, birthday.Date)
 			}
+
+			return nil
 		},
 	}
 
@@ -56,6 +65,7 @@ Example usage:
 	listBirthdaysCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
 	listBirthdaysCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
 	listBirthdaysCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	listBirthdaysCmd.Flags().BoolVar(&offline, "offline", helper.DefaultOffline(), "Serve the data from the local cache without contacting the server")
 	listBirthdaysCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
 
 	return listBirthdaysCmd
@@ -1,101 +1,253 @@
 package birthdays
 
 import (
+	"bufio"
 	"fmt"
 	"hbd-cli/api"
 	"hbd-cli/helper"
 	"hbd-cli/structs"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 // ModifyBirthday command
 func ModifyBirthday() *cobra.Command {
-	var id int64
-	var name, date, host, port, credsPath string
-	var ssl bool
+	var ids []string
+	var match, name, date, host, port, credsPath string
+	var month, concurrency int
+	var ssl, autoConfirm bool
 
 	var modifyBirthdayCmd = &cobra.Command{
-		Use:   "modify",
-		Short: "Modify an existing birthday",
-		Long:  `The modify-birthday command allows you to modify an existing birthday by providing its ID, new name, and/or new date. The ID is required, but if the name or date is not provided, the existing value will be used.
-		
+		Use:   "modify [ID or ID range...]",
+		Short: "Modify one or more birthdays",
+		Long:  `The modify-birthday command allows you to modify existing birthdays by providing their ID, new name, and/or new date. If the name or date is not provided, the existing value will be used.
+
+Several birthdays can be modified at once, e.g. to fix the dates of a bad import. They are selected
+like with the delete command: by ID, by ID range (e.g. 5-9), by a case-insensitive name expression
+(--match) and by birth month (--month). When several criteria are given, only the birthdays matching
+all of them are modified. Nothing is modified when an ID given on its own doesn't exist.
+
+When more than one birthday may be selected, --name or --date is required and a preview is shown
+before asking for confirmation, use --yes to skip it. The command exits with a non-zero status
+if any modification fails.
+
 Environment variables:
   HBD_CREDS_PATH - Path to the credentials file.
-  HBD_HOST - The host for the service. Defaults to
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
   HBD_PORT - The port for the service.
   HBD_SSL - Use SSL (https) for the connection.
 
 Example usage:
   hbd-cli birthdays modify --id=1 --name="John Doe" --date="2021-12-25" --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
+  hbd-cli birthdays modify 3 5-9 --date="1990-06-15" --yes
+  hbd-cli birthdays modify --match="^john" --month=12 --name="John Doe" --concurrency=8
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
 			// Load env vars
 			helper.LoadEnvVars()
 
-			// Load credentials
+			// Parse the IDs given as flags or arguments
+			parsedIDs, err := parseIDs(append(ids, args...))
+			if err != nil {
+				return helper.WithExitCode(selectionExitCode(err), helper.NewError("Error parsing IDs", err))
+			}
+
+			sel := selector{IDs: parsedIDs, Match: match, Month: month}
+			if sel.empty() {
+				return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error modifying birthdays", "at least one ID, --match or --month must be provided"))
+			}
+			_, err = sel.compile()
+			if err != nil {
+				return helper.WithExitCode(selectionExitCode(err), helper.NewError("Error parsing filters", err))
+			}
+
+			// A single ID modifies one birthday, anything else may select several
+			bulk := len(sel.IDs) != 1 || !sel.IDs[0].single() || sel.Match != "" || sel.Month != 0
+			if bulk && name == "" && date == "" {
+				return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error modifying birthdays", "--name or --date must be provided to modify several birthdays"))
+			}
+
+			// Validate the new values
+			if name != "" {
+				if err := helper.ValidateBirthdayName(name); err != nil {
+					return helper.WithExitCode(helper.ExitValidation, helper.NewError("Error modifying birthdays", err))
+				}
+			}
+			if date != "" {
+				if err := helper.ValidateBirthdayDate(date); err != nil {
+					return helper.WithExitCode(helper.ExitValidation, helper.NewError("Error modifying birthdays", err))
+				}
+			}
+
+			// Load the token
 			credsPath = filepath.Join(credsPath, host)
-			creds, err := helper.LoadCredentials(credsPath)
-			helper.HandleError("Error loading credentials from credentials file", err)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
 
 			// Create the URL
 			url := helper.GenUrl(host, port, ssl)
 
-			// If the ID is sent, but NOT the name or date, just look them up requesting /me
-			if id != 0 && (name == "" || date == "") {
-				// Get the user data
-				userData, err := api.GetUserData(url, creds.Token)
-				helper.HandleErrorExit("Error retrieving user data", err)
+			// Look up the current values, used for the missing name or date and for the journal,
+			// the cached values are used when the server is unreachable
+			userData, fetchedAt, err := helper.FetchUserData(cmd.ErrOrStderr(), url, token, false)
+			if err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
+			helper.PrintStaleNotice(cmd.ErrOrStderr(), fetchedAt)
+			offline := !fetchedAt.IsZero()
+
+			// Find the birthdays
+			msg := "Error modifying birthdays"
+			if !bulk {
+				msg = "Error modifying birthday"
+			}
+			selected, err := resolveSelection(sel, userData.Birthdays, msg)
+			if err != nil {
+				return err
+			}
+
+			// The new values, the existing ones are kept for the missing name or date
+			modified := make([]structs.BirthdayFull, len(selected))
+			for i, birthday := range selected {
+				modified[i] = structs.BirthdayFull{ID: birthday.ID, Name: name, Date: date}
+				if name == "" {
+					modified[i].Name = birthday.Name
+				}
+				if date == "" {
+					modified[i].Date = birthday.Date
+				}
+			}
+
+			if !bulk {
+				return modifyBirthday(cmd, url, token, offline, selected[0], modified[0])
+			}
+
+			// Print a preview of what will be modified
+			if offline {
+				fmt.Fprintln(out, "The server is unreachable, the modifications will be queued until 'hbd sync' is run.")
+			}
+			fmt.Fprintf(out, "The following %d birthday(s) will be modified:\n", len(selected))
+			for i, birthday := range selected {
+				fmt.Fprintf(out, "ID: %d, Name: %s, Date: %s -> Name: %s, Date: %s\n", birthday.ID, birthday.Name, birthday.Date, modified[i].Name, modified[i].Date)
+			}
 
-				// Find the birthday
-				for _, birthday := range userData.Birthdays {
-					if birthday.ID == id {
+			// If autoConfirm is not set, ask for confirmation
+			if !autoConfirm {
+				reader := bufio.NewReader(cmd.InOrStdin())
+				fmt.Fprint(out, "Are you sure you want to modify these birthdays? Type 'yes' to proceed: ")
+				confirmation, _ := reader.ReadString('\n')
+				confirmation = strings.TrimSpace(confirmation)
 
-						// If the name is empty, use the existing name
-						if name == "" {
-							name = birthday.Name
-						}
+				if confirmation != "yes" {
+					fmt.Fprintln(out, "Modification cancelled.")
+					return nil
+				}
+			}
 
-						// If the date is empty, use the existing date
-						if date == "" {
-							date = birthday.Date
-						}
+			// Modify the birthdays with bounded concurrency, unless the server is already known to be unreachable
+			errs := make([]error, len(selected))
+			if !offline {
+				errs = forEachConcurrently(modified, concurrency, func(birthday structs.BirthdayFull) error {
+					_, err := api.ModifyBirthday(url, token, structs.BirthdayNameDateModify(birthday))
+					return err
+				})
+			}
 
-						// Exit the loop
-						break
+			// Print the report
+			failed, queued := 0, 0
+			for i, birthday := range selected {
+				// Queue the modifications that couldn't reach the server
+				if offline || api.IsNetworkError(errs[i]) {
+					errs[i] = helper.QueueBirthdayChange(url, helper.JournalModify, &birthday, &modified[i])
+					if errs[i] == nil {
+						queued++
+						fmt.Fprintf(out, "QUEUED   ID: %d, Name: %s, Date: %s\n", birthday.ID, modified[i].Name, modified[i].Date)
+						continue
 					}
 				}
-			}
 
-			// Create the JSON payload
-			birthdayReq := structs.BirthdayNameDateModify{
-				ID:   id,
-				Name: name,
-				Date: date,
+				if errs[i] != nil {
+					failed++
+					fmt.Fprintf(out, "FAILED   ID: %d, Name: %s, Date: %s (%v)\n", birthday.ID, birthday.Name, birthday.Date, errs[i])
+					continue
+				}
+				fmt.Fprintf(out, "MODIFIED ID: %d, Name: %s, Date: %s\n", birthday.ID, modified[i].Name, modified[i].Date)
+
+				// Record the change so it can be undone
+				helper.RecordBirthdayChange(cmd.ErrOrStderr(), url, helper.JournalModify, &birthday, &modified[i])
+			}
+			fmt.Fprintf(out, "%d of %d birthday(s) modified successfully.\n", len(selected)-failed-queued, len(selected))
+			if queued > 0 {
+				fmt.Fprintf(out, "%d modification(s) queued, run 'hbd sync' once back online.\n", queued)
 			}
 
-			// Make the request
-			_, err = api.ModifyBirthday(url, creds.Token, birthdayReq)
-			helper.HandleErrorExit("Error modifying birthday", err)
+			if failed > 0 {
+				return helper.NewErrorStr("Error modifying birthdays", fmt.Sprintf("%d modification(s) failed", failed))
+			}
 
-			// Print success message
-			fmt.Printf("Birthday with ID %d modified successfully to %s on %s!\n", id, name, date)
+			return nil
 		},
 	}
 
 	// Add flags
-	modifyBirthdayCmd.Flags().Int64Var(&id, "id", 0, "ID of the birthday to modify (required)")
-	modifyBirthdayCmd.Flags().StringVar(&name, "name", "", "New name for the birthday")
-	modifyBirthdayCmd.Flags().StringVar(&date, "date", "", "New date for the birthday (format YYYY-MM-DD)")
+	modifyBirthdayCmd.Flags().StringSliceVar(&ids, "id", nil, "ID(s) or ID range(s) of the birthdays to modify, e.g. 1,3,5-9")
+	modifyBirthdayCmd.Flags().StringVar(&match, "match", "", "Modify birthdays whose name matches this case-insensitive expression")
+	modifyBirthdayCmd.Flags().IntVar(&month, "month", 0, "Modify birthdays in this month (1-12)")
+	modifyBirthdayCmd.Flags().StringVar(&name, "name", "", "New name for the birthdays")
+	modifyBirthdayCmd.Flags().StringVar(&date, "date", "", "New date for the birthdays (format YYYY-MM-DD)")
+	modifyBirthdayCmd.Flags().BoolVarP(&autoConfirm, "yes", "y", false, "Automatic yes to confirmation prompt")
+	modifyBirthdayCmd.Flags().IntVar(&concurrency, "concurrency", 4, "Maximum number of modifications running at the same time")
 	modifyBirthdayCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
 	modifyBirthdayCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
 	modifyBirthdayCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
 	modifyBirthdayCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
 
-	// Mark required flags
-	modifyBirthdayCmd.MarkFlagRequired("id")
+	// Complete the IDs and names of the birthdays
+	modifyBirthdayCmd.ValidArgsFunction = helper.CompleteBirthdayIDs
+	modifyBirthdayCmd.RegisterFlagCompletionFunc("id", helper.CompleteBirthdayIDs)
+	modifyBirthdayCmd.RegisterFlagCompletionFunc("match", helper.CompleteBirthdayNames)
+	modifyBirthdayCmd.RegisterFlagCompletionFunc("name", helper.CompleteBirthdayNames)
 
 	return modifyBirthdayCmd
 
 }
+
+// modifyBirthday modifies a single birthday, records it in the journal and prints the result,
+// the change is queued instead when the server is unreachable
+func modifyBirthday(cmd *cobra.Command, url, token string, offline bool, before, after structs.BirthdayFull) error {
+	out := cmd.OutOrStdout()
+
+	// Make the request, unless the server is already known to be unreachable
+	var err error
+	if !offline {
+		_, err = api.ModifyBirthday(url, token, structs.BirthdayNameDateModify(after))
+		offline = api.IsNetworkError(err)
+	}
+
+	// Queue the change when the server can't be reached
+	if offline {
+		err = helper.QueueBirthdayChange(url, helper.JournalModify, &before, &after)
+		if err != nil {
+			return helper.NewError("Error queueing birthday", err)
+		}
+		fmt.Fprintf(out, "Server unreachable, modification of birthday with ID %d queued. Run 'hbd sync' once back online.\n", after.ID)
+		return nil
+	}
+	if err != nil {
+		return helper.NewError("Error modifying birthday", err)
+	}
+
+	// Record the change so it can be undone
+	helper.RecordBirthdayChange(cmd.ErrOrStderr(), url, helper.JournalModify, &before, &after)
+
+	// Print success message
+	fmt.Fprintf(out, "Birthday with ID %d modified successfully to %s on %s!\n", after.ID, after.Name, after.Date)
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package birthdays
+
+import (
+	"errors"
+	"fmt"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// idRange is an inclusive range of birthday IDs, a single ID has the same start and end
+type idRange struct {
+	Start int64
+	End   int64
+}
+
+// single reports whether the range was given as a single ID
+func (r idRange) single() bool {
+	return r.Start == r.End
+}
+
+// contains reports whether the ID is in the range
+func (r idRange) contains(id int64) bool {
+	return id >= r.Start && id <= r.End
+}
+
+// rangeError is returned for IDs and months that are well-formed but out of range
+type rangeError struct {
+	msg string
+}
+
+func (e *rangeError) Error() string {
+	return e.msg
+}
+
+// selectionExitCode returns the exit code for an error parsing a selection: IDs and months out of
+// range are rejected values, anything else couldn't be parsed
+func selectionExitCode(err error) int {
+	var rangeErr *rangeError
+	if errors.As(err, &rangeErr) {
+		return helper.ExitValidation
+	}
+	return helper.ExitUsage
+}
+
+// selector picks birthdays by ID, ID range, name pattern and month
+type selector struct {
+	IDs   []idRange
+	Match string
+	Month int
+}
+
+// parseIDs parses IDs and ID ranges such as "1", "3,4" or "5-9",
+// ranges are kept as bounds so that wide ones don't have to be expanded
+func parseIDs(values []string) ([]idRange, error) {
+	var ids []idRange
+
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			// A single ID
+			from, to, isRange := strings.Cut(part, "-")
+			if !isRange {
+				id, err := strconv.ParseInt(part, 10, 64)
+				if err != nil && !errors.Is(err, strconv.ErrRange) {
+					return nil, fmt.Errorf("invalid ID %q", part)
+				}
+				if err != nil || id <= 0 {
+					return nil, &rangeError{fmt.Sprintf("invalid ID %q, must be between 1 and %d", part, int64(math.MaxInt64))}
+				}
+				ids = append(ids, idRange{Start: id, End: id})
+				continue
+			}
+
+			// An inclusive range of IDs
+			start, errStart := strconv.ParseInt(strings.TrimSpace(from), 10, 64)
+			end, errEnd := strconv.ParseInt(strings.TrimSpace(to), 10, 64)
+			for _, err := range []error{errStart, errEnd} {
+				if err != nil && !errors.Is(err, strconv.ErrRange) {
+					return nil, fmt.Errorf("invalid ID range %q", part)
+				}
+			}
+			if errStart != nil || errEnd != nil || start <= 0 || end < start {
+				return nil, &rangeError{fmt.Sprintf("invalid ID range %q, the IDs must be between 1 and %d and the first one must not be greater than the last one", part, int64(math.MaxInt64))}
+			}
+			ids = append(ids, idRange{Start: start, End: end})
+		}
+	}
+
+	return ids, nil
+}
+
+// wants reports whether the ID is in any of the selected IDs or ranges
+func (s selector) wants(id int64) bool {
+	for _, r := range s.IDs {
+		if r.contains(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// empty reports whether the selector would match every birthday
+func (s selector) empty() bool {
+	return len(s.IDs) == 0 && s.Match == "" && s.Month == 0
+}
+
+// compile validates the filters and returns the compiled name expression, if any
+func (s selector) compile() (*regexp.Regexp, error) {
+	if s.Month < 0 || s.Month > 12 {
+		return nil, &rangeError{fmt.Sprintf("invalid month %d, must be between 1 and 12", s.Month)}
+	}
+
+	if s.Match == "" {
+		return nil, nil
+	}
+
+	pattern, err := regexp.Compile("(?i)" + s.Match)
+	if err != nil {
+		return nil, fmt.Errorf("invalid match expression %q: %v", s.Match, err)
+	}
+
+	return pattern, nil
+}
+
+// apply returns the birthdays matching every criterion of the selector,
+// along with the IDs given on their own that don't exist. Gaps in ranges aren't reported.
+func (s selector) apply(birthdays []structs.BirthdayFull) ([]structs.BirthdayFull, []int64, error) {
+	pattern, err := s.compile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := map[int64]bool{}
+	var selected []structs.BirthdayFull
+	for _, birthday := range birthdays {
+		if len(s.IDs) > 0 && !s.wants(birthday.ID) {
+			continue
+		}
+		found[birthday.ID] = true
+
+		if pattern != nil && !pattern.MatchString(birthday.Name) {
+			continue
+		}
+
+		if s.Month != 0 {
+			date, err := helper.ParseBirthdayDate(birthday.Date)
+			if err != nil || int(date.Month()) != s.Month {
+				continue
+			}
+		}
+
+		selected = append(selected, birthday)
+	}
+
+	var missing []int64
+	for _, r := range s.IDs {
+		if r.single() && !found[r.Start] {
+			found[r.Start] = true
+			missing = append(missing, r.Start)
+		}
+	}
+
+	return selected, missing, nil
+}
+
+// resolveSelection returns the birthdays matching the selector. IDs given on their own that don't exist,
+// or a selection matching nothing, fail with a not found error before anything is changed
+func resolveSelection(sel selector, birthdays []structs.BirthdayFull, msg string) ([]structs.BirthdayFull, error) {
+	selected, missing, err := sel.apply(birthdays)
+	if err != nil {
+		return nil, helper.NewError("Error selecting birthdays", err)
+	}
+
+	if len(missing) > 0 {
+		ids := make([]string, len(missing))
+		for i, id := range missing {
+			ids[i] = strconv.FormatInt(id, 10)
+		}
+		return nil, helper.WithExitCode(helper.ExitNotFound, helper.NewErrorStr(msg, "no birthday with ID "+strings.Join(ids, ", ")))
+	}
+	if len(selected) == 0 {
+		return nil, helper.WithExitCode(helper.ExitNotFound, helper.NewErrorStr(msg, "no birthdays match the selection"))
+	}
+
+	return selected, nil
+}
+
+// forEachConcurrently runs fn on every birthday with at most `limit` calls in flight,
+// returning the error of each call in the same order as the birthdays
+func forEachConcurrently(birthdays []structs.BirthdayFull, limit int, fn func(structs.BirthdayFull) error) []error {
+	if limit < 1 {
+		limit = 1
+	}
+
+	errs := make([]error, len(birthdays))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	for i, birthday := range birthdays {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, birthday structs.BirthdayFull) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(birthday)
+		}(i, birthday)
+	}
+
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,119 @@
+package birthdays
+
+import (
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParseIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []idRange
+		// wantCode is the exit code of the error, 0 when there's none
+		wantCode int
+	}{
+		{"single", []string{"3"}, []idRange{{3, 3}}, 0},
+		{"list", []string{"3,4", "7"}, []idRange{{3, 3}, {4, 4}, {7, 7}}, 0},
+		{"range", []string{"5-9"}, []idRange{{5, 9}}, 0},
+		{"range with spaces", []string{" 5 - 9 "}, []idRange{{5, 9}}, 0},
+		{"wide range is not expanded", []string{"1-" + strconv.FormatInt(math.MaxInt64, 10)}, []idRange{{1, math.MaxInt64}}, 0},
+		{"empty parts are skipped", []string{"1,,2,"}, []idRange{{1, 1}, {2, 2}}, 0},
+		{"reversed range", []string{"9-5"}, nil, helper.ExitValidation},
+		{"zero", []string{"0"}, nil, helper.ExitValidation},
+		{"range from zero", []string{"0-3"}, nil, helper.ExitValidation},
+		{"overflow", []string{"99999999999999999999"}, nil, helper.ExitValidation},
+		{"range end overflow", []string{"1-99999999999999999999"}, nil, helper.ExitValidation},
+		{"negative", []string{"-3"}, nil, helper.ExitUsage},
+		{"garbage", []string{"abc"}, nil, helper.ExitUsage},
+		{"garbage range end", []string{"1-x"}, nil, helper.ExitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIDs(tt.values)
+			if (err != nil) != (tt.wantCode != 0) {
+				t.Fatalf("parseIDs() error = %v, want exit code %d", err, tt.wantCode)
+			}
+			if err != nil && selectionExitCode(err) != tt.wantCode {
+				t.Errorf("selectionExitCode(%v) = %d, want %d", err, selectionExitCode(err), tt.wantCode)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectorApply(t *testing.T) {
+	birthdays := []structs.BirthdayFull{
+		{ID: 1, Name: "John Doe", Date: "1990-12-01"},
+		{ID: 2, Name: "Jane Doe", Date: "1991-12-24"},
+		{ID: 3, Name: "Johnny Cash", Date: "1932-02-26"},
+		{ID: 5, Name: "John Smith", Date: "1985-12-10"},
+		{ID: 6, Name: "Mary Major", Date: "1970-06-15"},
+	}
+
+	tests := []struct {
+		name        string
+		sel         selector
+		wantIDs     []int64
+		wantMissing []int64
+	}{
+		{"no filters", selector{}, []int64{1, 2, 3, 5, 6}, nil},
+		{"single IDs", selector{IDs: []idRange{{2, 2}, {6, 6}}}, []int64{2, 6}, nil},
+		{"missing single ID", selector{IDs: []idRange{{1, 1}, {4, 4}, {4, 4}}}, []int64{1}, []int64{4}},
+		{"range gaps are not missing", selector{IDs: []idRange{{2, 5}}}, []int64{2, 3, 5}, nil},
+		{"wide range", selector{IDs: []idRange{{1, math.MaxInt64}}}, []int64{1, 2, 3, 5, 6}, nil},
+		{"match is case-insensitive", selector{Match: "^john"}, []int64{1, 3, 5}, nil},
+		{"month", selector{Month: 12}, []int64{1, 2, 5}, nil},
+		{"ID and match and month", selector{IDs: []idRange{{1, 3}, {5, 5}}, Match: "^john", Month: 12}, []int64{1, 5}, nil},
+		{"match excludes found ID", selector{IDs: []idRange{{6, 6}}, Match: "doe"}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, missing, err := tt.sel.apply(birthdays)
+			if err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+
+			var ids []int64
+			for _, birthday := range selected {
+				ids = append(ids, birthday.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("apply() selected = %v, want %v", ids, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("apply() missing = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestSelectorApplyInvalid(t *testing.T) {
+	tests := []struct {
+		sel      selector
+		wantCode int
+	}{
+		{selector{Month: 13}, helper.ExitValidation},
+		{selector{Month: -1}, helper.ExitValidation},
+		{selector{Match: "("}, helper.ExitUsage},
+	}
+
+	for _, tt := range tests {
+		_, _, err := tt.sel.apply(nil)
+		if err == nil {
+			t.Errorf("apply() with %+v returned no error", tt.sel)
+			continue
+		}
+		if code := selectionExitCode(err); code != tt.wantCode {
+			t.Errorf("selectionExitCode() for %+v = %d, want %d", tt.sel, code, tt.wantCode)
+		}
+	}
+}
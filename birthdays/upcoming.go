@@ -0,0 +1,128 @@
+package birthdays
+
+import (
+	"fmt"
+	"hbd-cli/helper"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// upcomingBirthday is a birthday along with its next occurrence
+type upcomingBirthday struct {
+	ID       int64
+	Name     string
+	Date     string
+	Next     time.Time
+	DaysLeft int
+	Turns    int
+}
+
+// UpcomingBirthdays command
+func UpcomingBirthdays() *cobra.Command {
+	var host, port, credsPath, leapDayPolicy string
+	var days int
+	var ssl, offline bool
+
+	var upcomingBirthdaysCmd = &cobra.Command{
+		Use:   "upcoming",
+		Short: "List upcoming birthdays",
+		Long: `The upcoming command lists the birthdays coming up in the next days, along with
+how many days are left and how old the person will turn.
+
+People born on February 29 celebrate on February 28 or March 1 in non-leap years,
+depending on the leap day policy (feb28 or mar1).
+
+Environment variables:
+  HBD_CREDS_PATH - Path to the credentials file.
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service.
+  HBD_SSL - Use SSL (https) for the connection.
+  HBD_OFFLINE - Serve the data from the local cache without contacting the server.
+  HBD_LEAP_DAY_POLICY - When to celebrate February 29 birthdays in non-leap years (feb28 or mar1). Defaults to feb28.
+
+Example usage:
+  hbd-cli birthdays upcoming --days=30 --leap-day-policy=mar1 --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+
+			// Validate the leap day policy
+			if err := helper.ValidateLeapDayPolicy(leapDayPolicy); err != nil {
+				return helper.WithExitCode(helper.ExitUsage, helper.NewError("Error validating flags", err))
+			}
+
+			// Load the token, it's not needed for cached data
+			credsPath = filepath.Join(credsPath, host)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil && !offline {
+				return err
+			}
+
+			// Create the URL
+			url := helper.GenUrl(host, port, ssl)
+
+			// Make the request to get user data, or use the cache when offline
+			userData, fetchedAt, err := helper.FetchUserData(cmd.ErrOrStderr(), url, token, offline)
+			if err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
+			helper.PrintStaleNotice(cmd.ErrOrStderr(), fetchedAt)
+
+			// Compute the next occurrence of each birthday
+			now := time.Now()
+			var upcoming []upcomingBirthday
+			for _, birthday := range userData.Birthdays {
+				birth, err := helper.ParseBirthdayDate(birthday.Date)
+				if err != nil {
+					helper.HandleError(cmd.ErrOrStderr(), fmt.Sprintf("Skipping birthday with ID %d", birthday.ID), err)
+					continue
+				}
+
+				daysLeft := helper.DaysUntil(birth, now, leapDayPolicy)
+				if daysLeft > days {
+					continue
+				}
+
+				next := helper.NextOccurrence(birth, now, leapDayPolicy)
+				upcoming = append(upcoming, upcomingBirthday{
+					ID:       birthday.ID,
+					Name:     birthday.Name,
+					Date:     birthday.Date,
+					Next:     next,
+					DaysLeft: daysLeft,
+					Turns:    helper.Age(birth, next, leapDayPolicy),
+				})
+			}
+
+			// Soonest first
+			sort.SliceStable(upcoming, func(i, j int) bool {
+				return upcoming[i].DaysLeft < upcoming[j].DaysLeft
+			})
+
+			// Print the upcoming birthdays
+			fmt.Fprintf(out, "Birthdays in the next %d days:\n", days)
+			for _, birthday := range upcoming {
+				fmt.Fprintf(out, "ID: %d, Name: %s, Date: %s, Next: %s, In: %d days, Turns: %d\n", birthday.ID, birthday.Name, birthday.Date, birthday.Next.Format(helper.DateLayout), birthday.DaysLeft, birthday.Turns)
+			}
+
+			return nil
+		},
+	}
+
+	// Add flags
+	upcomingBirthdaysCmd.Flags().IntVar(&days, "days", 30, "Number of days to look ahead")
+	upcomingBirthdaysCmd.Flags().StringVar(&leapDayPolicy, "leap-day-policy", helper.DefaultLeapDayPolicy(), "When to celebrate February 29 birthdays in non-leap years (feb28 or mar1)")
+	upcomingBirthdaysCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	upcomingBirthdaysCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
+	upcomingBirthdaysCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	upcomingBirthdaysCmd.Flags().BoolVar(&offline, "offline", helper.DefaultOffline(), "Serve the data from the local cache without contacting the server")
+	upcomingBirthdaysCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
+
+	return upcomingBirthdaysCmd
+}
@@ -0,0 +1,432 @@
+// Package devserver implements a mock HBD backend for local development and tests.
+//
+// It serves every endpoint used by package api, with the same request and response
+// shapes as package structs, real JWT issuance and an in-memory or JSON file store.
+// Reminders are never sent: check-birthdays only reports success.
+package devserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hbd-cli/structs"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultTokenDuration is the token duration used when the X-Jwt-Token-Duration header is missing
+const DefaultTokenDuration = 720 * time.Hour
+
+// reminderTimePattern matches reminder times in HH:MM format
+var reminderTimePattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// Options configures a Server
+type Options struct {
+	// StorePath is the JSON file the accounts are saved to, they are only kept in memory when empty
+	StorePath string
+
+	// Secret signs the JWT tokens, a random one is generated when empty
+	Secret []byte
+}
+
+// Server is a mock HBD backend, it implements http.Handler
+type Server struct {
+	store  *store
+	secret []byte
+	mux    *http.ServeMux
+}
+
+// New creates a Server
+func New(opts Options) (*Server, error) {
+	st, err := newStore(opts.StorePath)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := opts.Secret
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Server{store: st, secret: secret, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /api/health", s.health)
+	s.mux.HandleFunc("GET /api/generate-password", s.generatePassword)
+	s.mux.HandleFunc("POST /api/register", s.register)
+	s.mux.HandleFunc("POST /api/login", s.login)
+	s.mux.HandleFunc("GET /api/me", s.authenticated(s.me))
+	s.mux.HandleFunc("PUT /api/modify-user", s.authenticated(s.modifyUser))
+	s.mux.HandleFunc("DELETE /api/delete-user", s.authenticated(s.deleteUser))
+	s.mux.HandleFunc("POST /api/add-birthday", s.authenticated(s.addBirthday))
+	s.mux.HandleFunc("PUT /api/modify-birthday", s.authenticated(s.modifyBirthday))
+	s.mux.HandleFunc("DELETE /api/delete-birthday", s.authenticated(s.deleteBirthday))
+	s.mux.HandleFunc("PATCH /api/check-birthdays", s.authenticated(s.checkBirthdays))
+
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes an error response in the shape of structs.Error
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, structs.Error{Error: msg})
+}
+
+// decode reads a JSON request body
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+// issueToken signs a token for the email, valid for the duration asked in the X-Jwt-Token-Duration header (in hours)
+func (s *Server) issueToken(r *http.Request, email string) (string, error) {
+	duration := DefaultTokenDuration
+	if header := r.Header.Get("X-Jwt-Token-Duration"); header != "" {
+		hours, err := strconv.Atoi(header)
+		if err != nil || hours <= 0 {
+			return "", fmt.Errorf("invalid token duration %q", header)
+		}
+		duration = time.Duration(hours) * time.Hour
+	}
+
+	now := time.Now()
+	claims := structs.Claims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+// authenticated wraps a handler that needs a valid bearer token, passing it the user
+func (s *Server) authenticated(next func(http.ResponseWriter, *http.Request, *user)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		var claims structs.Claims
+		_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+			return s.secret, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+		if err != nil {
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				writeError(w, http.StatusUnauthorized, "token expired")
+				return
+			}
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		s.store.mu.Lock()
+		defer s.store.mu.Unlock()
+
+		u := s.store.userByEmail(claims.Email)
+		if u == nil {
+			writeError(w, http.StatusUnauthorized, "user not found")
+			return
+		}
+
+		next(w, r, u)
+	}
+}
+
+// loginSuccess returns the login response for a user
+func loginSuccess(token string, u *user) structs.LoginSuccess {
+	data := u.userData()
+	return structs.LoginSuccess{
+		Token:             token,
+		TelegramBotAPIKey: data.TelegramBotAPIKey,
+		TelegramUserID:    data.TelegramUserID,
+		ReminderTime:      data.ReminderTime,
+		Timezone:          data.Timezone,
+		Birthdays:         data.Birthdays,
+	}
+}
+
+// validateSettings checks the reminder time and timezone of an account
+func validateSettings(reminderTime, timezone string) error {
+	if !reminderTimePattern.MatchString(reminderTime) {
+		return fmt.Errorf("invalid reminder time %q, must be in HH:MM format", reminderTime)
+	}
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" {
+		return fmt.Errorf("invalid timezone %q", timezone)
+	}
+	return nil
+}
+
+// validateBirthday checks the name and date of a birthday
+func validateBirthday(name, date string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return fmt.Errorf("invalid date %q, must be in YYYY-MM-DD format", date)
+	}
+	return nil
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, structs.Ready{Status: "ready"})
+}
+
+func (s *Server) generatePassword(w http.ResponseWriter, r *http.Request) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		writeError(w, http.StatusInternalServerError, "error generating password")
+		return
+	}
+	writeJSON(w, http.StatusOK, structs.Password{Password: hex.EncodeToString(buf)})
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	var req structs.RegisterRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	if req.Email == "" || req.Password == "" || req.TelegramBotAPIKey == "" || req.TelegramUserID == "" {
+		writeError(w, http.StatusBadRequest, "email, password, telegram_bot_api_key and telegram_user_id are required")
+		return
+	}
+	if err := validateSettings(req.ReminderTime, req.Timezone); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	if s.store.userByEmail(req.Email) != nil {
+		writeError(w, http.StatusConflict, "email already registered")
+		return
+	}
+
+	token, err := s.issueToken(r, req.Email)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	u := &user{
+		ID:                s.store.NextUserID,
+		Email:             req.Email,
+		PasswordHash:      hashPassword(req.Password),
+		ReminderTime:      req.ReminderTime,
+		Timezone:          req.Timezone,
+		TelegramBotAPIKey: req.TelegramBotAPIKey,
+		TelegramUserID:    req.TelegramUserID,
+		Birthdays:         []structs.BirthdayFull{},
+	}
+	s.store.NextUserID++
+	s.store.Users = append(s.store.Users, u)
+
+	if err := s.store.save(); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, loginSuccess(token, u))
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var req structs.LoginRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	u := s.store.userByEmail(req.Email)
+	if u == nil || u.PasswordHash != hashPassword(req.Password) {
+		writeError(w, http.StatusUnauthorized, "invalid email or password")
+		return
+	}
+
+	token, err := s.issueToken(r, u.Email)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, loginSuccess(token, u))
+}
+
+func (s *Server) me(w http.ResponseWriter, r *http.Request, u *user) {
+	writeJSON(w, http.StatusOK, u.userData())
+}
+
+func (s *Server) modifyUser(w http.ResponseWriter, r *http.Request, u *user) {
+	var req structs.ModifyUserRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	if req.NewTelegramBotAPIKey == "" || req.NewTelegramUserID == "" {
+		writeError(w, http.StatusBadRequest, "new_telegram_bot_api_key and new_telegram_user_id are required")
+		return
+	}
+	if err := validateSettings(req.NewReminderTime, req.NewTimezone); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.NewEmail != "" && req.NewEmail != u.Email && s.store.userByEmail(req.NewEmail) != nil {
+		writeError(w, http.StatusConflict, "email already registered")
+		return
+	}
+
+	u.ReminderTime = req.NewReminderTime
+	u.Timezone = req.NewTimezone
+	u.TelegramBotAPIKey = req.NewTelegramBotAPIKey
+	u.TelegramUserID = req.NewTelegramUserID
+
+	// Changing the email or password issues a new token
+	reauth := req.NewEmail != "" || req.NewPassword != ""
+	if req.NewEmail != "" {
+		u.Email = req.NewEmail
+	}
+	if req.NewPassword != "" {
+		u.PasswordHash = hashPassword(req.NewPassword)
+	}
+
+	if err := s.store.save(); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !reauth {
+		writeJSON(w, http.StatusOK, u.userData())
+		return
+	}
+
+	token, err := s.issueToken(r, u.Email)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, loginSuccess(token, u))
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request, u *user) {
+	for i, candidate := range s.store.Users {
+		if candidate == u {
+			s.store.Users = append(s.store.Users[:i], s.store.Users[i+1:]...)
+			break
+		}
+	}
+
+	if err := s.store.save(); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, structs.Success{Success: true})
+}
+
+func (s *Server) addBirthday(w http.ResponseWriter, r *http.Request, u *user) {
+	var req structs.BirthdayNameDateAdd
+	if !decode(w, r, &req) {
+		return
+	}
+
+	if err := validateBirthday(req.Name, req.Date); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	birthday := structs.BirthdayFull{ID: s.store.NextBirthdayID, Name: req.Name, Date: req.Date}
+	s.store.NextBirthdayID++
+	u.Birthdays = append(u.Birthdays, birthday)
+
+	if err := s.store.save(); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, birthday)
+}
+
+func (s *Server) modifyBirthday(w http.ResponseWriter, r *http.Request, u *user) {
+	var req structs.BirthdayNameDateModify
+	if !decode(w, r, &req) {
+		return
+	}
+
+	if err := validateBirthday(req.Name, req.Date); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for i := range u.Birthdays {
+		if u.Birthdays[i].ID == req.ID {
+			u.Birthdays[i].Name = req.Name
+			u.Birthdays[i].Date = req.Date
+
+			if err := s.store.save(); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			writeJSON(w, http.StatusOK, structs.Success{Success: true})
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "birthday not found")
+}
+
+func (s *Server) deleteBirthday(w http.ResponseWriter, r *http.Request, u *user) {
+	var req structs.BirthdayNameDateModify
+	if !decode(w, r, &req) {
+		return
+	}
+
+	for i := range u.Birthdays {
+		if u.Birthdays[i].ID == req.ID {
+			u.Birthdays = append(u.Birthdays[:i], u.Birthdays[i+1:]...)
+
+			if err := s.store.save(); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			writeJSON(w, http.StatusOK, structs.Success{Success: true})
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "birthday not found")
+}
+
+func (s *Server) checkBirthdays(w http.ResponseWriter, r *http.Request, u *user) {
+	writeJSON(w, http.StatusOK, structs.Success{Success: true})
+}
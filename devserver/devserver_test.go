@@ -0,0 +1,170 @@
+package devserver
+
+import (
+	"hbd-cli/api"
+	"hbd-cli/structs"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+	server, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+var testRegistration = structs.RegisterRequest{
+	Email:             "dev@hbd.lotiguere.com",
+	Password:          "secret",
+	ReminderTime:      "09:00",
+	Timezone:          "Europe/Madrid",
+	TelegramBotAPIKey: "270485614:AAHfiqksKZ8WmR2zSjiQ7jd8Eud81ggE3e-3",
+	TelegramUserID:    "123456789",
+}
+
+func TestAccountLifecycle(t *testing.T) {
+	ts := newTestServer(t, Options{})
+
+	ready, err := api.CheckHealth(ts.URL)
+	if err != nil || ready.Status != "ready" {
+		t.Fatalf("CheckHealth() = %+v, %v", ready, err)
+	}
+
+	registered, err := api.Register(ts.URL, testRegistration, 1)
+	if err != nil || registered.Token == "" {
+		t.Fatalf("Register() = %+v, %v", registered, err)
+	}
+
+	if _, err := api.Register(ts.URL, testRegistration, 1); err == nil {
+		t.Fatal("Register() with a taken email succeeded")
+	}
+
+	if _, err := api.Login(ts.URL, structs.LoginRequest{Email: testRegistration.Email, Password: "wrong"}, 1); err == nil {
+		t.Fatal("Login() with a wrong password succeeded")
+	}
+
+	login, err := api.Login(ts.URL, structs.LoginRequest{Email: testRegistration.Email, Password: testRegistration.Password}, 1)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	token := login.Token
+
+	added, err := api.AddBirthday(ts.URL, token, structs.BirthdayNameDateAdd{Name: "John Doe", Date: "2000-02-29"})
+	if err != nil || added.ID == 0 {
+		t.Fatalf("AddBirthday() = %+v, %v", added, err)
+	}
+
+	if _, err := api.ModifyBirthday(ts.URL, token, structs.BirthdayNameDateModify{ID: added.ID, Name: "Jane Doe", Date: "2000-03-01"}); err != nil {
+		t.Fatalf("ModifyBirthday() error = %v", err)
+	}
+
+	if _, err := api.ModifyBirthday(ts.URL, token, structs.BirthdayNameDateModify{ID: added.ID + 1, Name: "Nobody", Date: "2000-03-01"}); err == nil {
+		t.Fatal("ModifyBirthday() of a missing birthday succeeded")
+	}
+
+	me, err := api.GetUserData(ts.URL, token)
+	if err != nil {
+		t.Fatalf("GetUserData() error = %v", err)
+	}
+	want := structs.BirthdayFull{ID: added.ID, Name: "Jane Doe", Date: "2000-03-01"}
+	if len(me.Birthdays) != 1 || me.Birthdays[0] != want || me.Timezone != testRegistration.Timezone {
+		t.Fatalf("GetUserData() = %+v, want the modified birthday %+v", me, want)
+	}
+
+	if _, err := api.CheckBirthdays(ts.URL, token); err != nil {
+		t.Fatalf("CheckBirthdays() error = %v", err)
+	}
+
+	if _, err := api.DeleteBirthday(ts.URL, token, structs.BirthdayNameDateModify{ID: added.ID}); err != nil {
+		t.Fatalf("DeleteBirthday() error = %v", err)
+	}
+
+	updated, err := api.ModifyUserWithoutEmail(ts.URL, token, structs.ModifyUserRequest{
+		NewReminderTime:      "10:30",
+		NewTimezone:          "UTC",
+		NewTelegramBotAPIKey: testRegistration.TelegramBotAPIKey,
+		NewTelegramUserID:    testRegistration.TelegramUserID,
+	})
+	if err != nil || updated.ReminderTime != "10:30" || len(updated.Birthdays) != 0 {
+		t.Fatalf("ModifyUserWithoutEmail() = %+v, %v", updated, err)
+	}
+
+	reauth, err := api.ModifyUserWithEmail(ts.URL, token, structs.ModifyUserRequest{
+		NewEmail:             "new@hbd.lotiguere.com",
+		NewReminderTime:      "10:30",
+		NewTimezone:          "UTC",
+		NewTelegramBotAPIKey: testRegistration.TelegramBotAPIKey,
+		NewTelegramUserID:    testRegistration.TelegramUserID,
+	}, 1)
+	if err != nil || reauth.Token == "" {
+		t.Fatalf("ModifyUserWithEmail() = %+v, %v", reauth, err)
+	}
+
+	// The old token belongs to an email that no longer exists
+	if _, err := api.GetUserData(ts.URL, token); err == nil {
+		t.Fatal("GetUserData() with the token of the old email succeeded")
+	}
+
+	if _, err := api.DeleteUser(ts.URL, reauth.Token); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := api.GetUserData(ts.URL, reauth.Token); err == nil {
+		t.Fatal("GetUserData() after DeleteUser() succeeded")
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	ts := newTestServer(t, Options{})
+
+	password, err := api.GeneratePassword(ts.URL)
+	if err != nil || len(password.Password) != 64 {
+		t.Fatalf("GeneratePassword() = %+v, %v", password, err)
+	}
+}
+
+func TestRejectsInvalidTokens(t *testing.T) {
+	ts := newTestServer(t, Options{Secret: []byte("one")})
+	other := newTestServer(t, Options{Secret: []byte("other")})
+
+	registered, err := api.Register(other.URL, testRegistration, 1)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if _, err := api.Register(ts.URL, testRegistration, 1); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if _, err := api.GetUserData(ts.URL, registered.Token); err == nil {
+		t.Fatal("GetUserData() with a token signed by another secret succeeded")
+	}
+	if _, err := api.GetUserData(ts.URL, ""); err == nil {
+		t.Fatal("GetUserData() without a token succeeded")
+	}
+}
+
+func TestStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	opts := Options{StorePath: path, Secret: []byte("secret")}
+
+	first := newTestServer(t, opts)
+	registered, err := api.Register(first.URL, testRegistration, 1)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if _, err := api.AddBirthday(first.URL, registered.Token, structs.BirthdayNameDateAdd{Name: "John Doe", Date: "1990-12-01"}); err != nil {
+		t.Fatalf("AddBirthday() error = %v", err)
+	}
+
+	second := newTestServer(t, opts)
+	me, err := api.GetUserData(second.URL, registered.Token)
+	if err != nil || len(me.Birthdays) != 1 {
+		t.Fatalf("GetUserData() after restart = %+v, %v", me, err)
+	}
+}
@@ -0,0 +1,109 @@
+package devserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hbd-cli/structs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// user is an account as kept by the store
+type user struct {
+	ID                int64                  `json:"id"`
+	Email             string                 `json:"email"`
+	PasswordHash      string                 `json:"password_hash"`
+	ReminderTime      string                 `json:"reminder_time"`
+	Timezone          string                 `json:"timezone"`
+	TelegramBotAPIKey string                 `json:"telegram_bot_api_key"`
+	TelegramUserID    string                 `json:"telegram_user_id"`
+	Birthdays         []structs.BirthdayFull `json:"birthdays"`
+}
+
+// userData returns the user as sent by the /api/me endpoint
+func (u *user) userData() *structs.UserData {
+	birthdays := make([]structs.BirthdayFull, len(u.Birthdays))
+	copy(birthdays, u.Birthdays)
+
+	return &structs.UserData{
+		ID:                u.ID,
+		TelegramBotAPIKey: u.TelegramBotAPIKey,
+		TelegramUserID:    u.TelegramUserID,
+		ReminderTime:      u.ReminderTime,
+		Timezone:          u.Timezone,
+		Birthdays:         birthdays,
+	}
+}
+
+// store keeps the accounts in memory, saving them to a JSON file after every change when a path is set
+type store struct {
+	mu   sync.Mutex
+	path string
+
+	Users          []*user `json:"users"`
+	NextUserID     int64   `json:"next_user_id"`
+	NextBirthdayID int64   `json:"next_birthday_id"`
+}
+
+// newStore creates a store, loading the JSON file at path if there is one
+func newStore(path string) (*store, error) {
+	s := &store{path: path, NextUserID: 1, NextBirthdayID: 1}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("error decoding store %s: %v", path, err)
+	}
+
+	return s, nil
+}
+
+// save writes the store to its JSON file, callers must hold the lock
+func (s *store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// userByEmail finds a user, callers must hold the lock
+func (s *store) userByEmail(email string) *user {
+	for _, u := range s.Users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return nil
+}
+
+// hashPassword hashes a password for storage
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,115 @@
+package general
+
+import (
+	"fmt"
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func Backup() *cobra.Command {
+	var host, port, credsPath, backupsPath string
+	var keep int
+	var ssl, list bool
+
+	var backupCmd = &cobra.Command{
+		Use:   "backup",
+		Short: "Save a local snapshot of your account",
+		Long: `The backup command saves a timestamped snapshot of your account settings and
+birthdays to the backups directory (default is ~/.hbd/backups/<host>).
+
+Only the most recent snapshots are kept, older ones are removed according to --keep.
+Snapshots can be brought back with the restore command.
+
+Environment variables:
+  HBD_CREDS_PATH - Path to the credentials file.
+  HBD_BACKUPS_PATH - Path to the backups directory.
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service.
+  HBD_SSL - Use SSL (https) for the connection.
+
+Example usage:
+  hbd-cli backup --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials" --keep=20
+  hbd-cli backup --list --host="hbd.lotiguere.com"
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+
+			// Snapshots are kept per host, like credentials
+			backupsPath = filepath.Join(backupsPath, host)
+
+			// List the existing snapshots
+			if list {
+				paths, err := helper.ListSnapshots(backupsPath)
+				if err != nil {
+					return helper.NewError("Error listing snapshots", err)
+				}
+
+				if len(paths) == 0 {
+					fmt.Fprintf(out, "No snapshots found in %s\n", backupsPath)
+					return nil
+				}
+				for _, path := range paths {
+					fmt.Fprintln(out, path)
+				}
+				return nil
+			}
+
+			// Load the token
+			credsPath = filepath.Join(credsPath, host)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
+
+			// Create the URL
+			url := helper.GenUrl(host, port, ssl)
+
+			// Make the request to get user data
+			userData, err := api.GetUserData(url, token)
+			if err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
+
+			// Save the snapshot
+			path, err := helper.SaveSnapshot(backupsPath, &helper.Snapshot{
+				CreatedAt: time.Now().UTC(),
+				Host:      host,
+				UserData:  *userData,
+			})
+			if err != nil {
+				return helper.NewError("Error saving snapshot", err)
+			}
+			fmt.Fprintf(out, "Snapshot with %d birthday(s) saved to %s\n", len(userData.Birthdays), path)
+
+			// Apply the retention policy
+			removed, err := helper.PruneSnapshots(backupsPath, keep)
+			if err != nil {
+				return helper.NewError("Error applying retention policy", err)
+			}
+			if len(removed) > 0 {
+				fmt.Fprintf(out, "Removed %d old snapshot(s), keeping the latest %d\n", len(removed), keep)
+			}
+
+			return nil
+		},
+	}
+
+	// Add flags to the backup command
+	backupCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	backupCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
+	backupCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	backupCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
+	backupCmd.Flags().StringVar(&backupsPath, "backups-path", helper.GetDefaultBackupsPath(), "Path to the backups directory")
+	backupCmd.Flags().IntVar(&keep, "keep", 10, "Number of snapshots to keep, 0 keeps all of them")
+	backupCmd.Flags().BoolVar(&list, "list", false, "List the existing snapshots instead of creating one")
+
+	// Return the backup command
+	return backupCmd
+}
@@ -0,0 +1,85 @@
+package general
+
+import (
+	"fmt"
+	"hbd-cli/devserver"
+	"hbd-cli/helper"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func DevServer() *cobra.Command {
+	var listen, storePath, secret string
+
+	var devServerCmd = &cobra.Command{
+		Use:   "dev-server",
+		Short: "Run a mock HBD server for local development",
+		Long: `The dev-server command runs a mock HBD backend implementing every endpoint used by
+the CLI, for local development and tests. No reminders are sent.
+
+Accounts are kept in memory, or in a JSON file with --store so they survive restarts.
+Tokens are real JWTs, signed with --secret or a random secret generated at startup.
+
+Environment variables:
+  HBD_DEV_SERVER_SECRET - The secret used to sign the JWT tokens.
+
+Example usage:
+  hbd-cli dev-server --listen="127.0.0.1:8417" --store="~/.hbd/dev-server.json"
+  hbd-cli auth register --host="127.0.0.1" --port="8417" --email="dev@hbd.lotiguere.com" ...
+  hbd-cli dev-server --listen="unix:///tmp/hbd.sock"
+  hbd-cli auth register --url="unix:///tmp/hbd.sock" --email="dev@hbd.lotiguere.com" ...
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+			if secret == "" {
+				secret = viper.GetString("HBD_DEV_SERVER_SECRET")
+			}
+
+			// Create the server
+			if storePath != "" {
+				storePath = helper.InterpretTildeAsHomeDir(storePath)
+			}
+			server, err := devserver.New(devserver.Options{StorePath: storePath, Secret: []byte(secret)})
+			if err != nil {
+				return helper.NewError("Error creating dev server", err)
+			}
+
+			if storePath != "" {
+				fmt.Fprintf(out, "Storing accounts in %s\n", storePath)
+			} else {
+				fmt.Fprintln(out, "Storing accounts in memory, they will be lost on exit")
+			}
+			// Listen on a Unix socket for unix:///path/to/socket, like servers behind a reverse proxy
+			if socket, ok := strings.CutPrefix(listen, "unix://"); ok {
+				listener, err := net.Listen("unix", socket)
+				if err != nil {
+					return helper.NewError("Error running dev server", err)
+				}
+				defer listener.Close()
+
+				fmt.Fprintf(out, "HBD dev server listening on %s\n", listen)
+				return helper.NewError("Error running dev server", http.Serve(listener, server))
+			}
+
+			fmt.Fprintf(out, "HBD dev server listening on http://%s\n", listen)
+
+			// Serve until interrupted
+			return helper.NewError("Error running dev server", http.ListenAndServe(listen, server))
+		},
+	}
+
+	// Add flags to the dev server command
+	devServerCmd.Flags().StringVar(&listen, "listen", "127.0.0.1:8417", "Address to listen on, or unix:///path/to/socket")
+	devServerCmd.Flags().StringVar(&storePath, "store", "", "JSON file to keep the accounts in, in memory if empty")
+	devServerCmd.Flags().StringVar(&secret, "secret", "", "Secret used to sign the JWT tokens, random if empty")
+
+	// Return the dev server command
+	return devServerCmd
+}
@@ -0,0 +1,517 @@
+package general
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Statuses of the doctor checks, skipped checks depend on one that failed or don't apply
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// Thresholds of the doctor checks
+const (
+	certExpiryWarning  = 14 * 24 * time.Hour
+	tokenExpiryWarning = 24 * time.Hour
+	slowHealthWarning  = time.Second
+	clockSkewWarning   = 30 * time.Second
+	clockSkewFailure   = 5 * time.Minute
+)
+
+// doctorCheck is the result of a single check
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// doctorReport is the result of every check, printed as is with --output json
+type doctorReport struct {
+	URL    string        `json:"url"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+// doctor runs the checks in order, later checks use what the earlier ones found
+type doctor struct {
+	cmd       *cobra.Command
+	host      string
+	port      string
+	ssl       bool
+	credsPath string
+	timeout   time.Duration
+	url       string
+	// socket is the Unix socket the service is reached through, if any
+	socket string
+
+	version       string
+	latestVersion func() (string, error)
+
+	resolved  bool
+	reachable bool
+	probe     *api.HealthProbe
+	report    doctorReport
+}
+
+func Doctor(version string, latestVersion func() (string, error)) *cobra.Command {
+	var host string
+	var port string
+	var ssl bool
+	var credsPath string
+	var timeout time.Duration
+
+	var doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the connection to the HBD service and the local setup",
+		Long: `The doctor command runs a checklist of everything hbd needs to work, from resolving the host
+to the validity of the saved token, and prints whether each check passed, warned or failed.
+
+Checks:
+  config - The URL, and whether the host, port and SSL come from flags, env vars or defaults.
+  dns - The host resolves to an address.
+  tcp - A connection can be opened to the host and port, or the Unix socket.
+  tls - The TLS handshake succeeds with the TLS flags and pins, and the certificate is not about to expire, when --ssl is set.
+  health - The health endpoint answers that the service is ready, and how fast.
+  credentials - The credentials file is only readable by the user (0600, in a 0700 directory).
+  token - The saved token is a JWT that hasn't expired and is accepted by the server.
+  clock - The local clock agrees with the Date header of the server, tokens expire early or late otherwise.
+  version - The CLI is the latest release, skipped for development builds.
+
+Use --output json for a JSON report. The command fails if any check fails.
+
+Environment variables:
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service.
+  HBD_SSL - Use SSL (https) for the connection.
+  HBD_URL - Full URL of the service, instead of HBD_HOST, HBD_PORT and HBD_SSL.
+  HBD_CREDS_PATH - Path to the credentials file.
+  HBD_TOKEN - JWT token used when there's no credentials file.
+
+Example usage:
+  hbd-cli doctor --host="hbd.lotiguere.com" --ssl
+  hbd-cli doctor --output json
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+
+			d := &doctor{
+				cmd:           cmd,
+				host:          host,
+				port:          port,
+				ssl:           ssl,
+				credsPath:     filepath.Join(credsPath, host),
+				timeout:       timeout,
+				url:           helper.GenUrl(host, port, ssl),
+				socket:        helper.SocketPath(),
+				version:       version,
+				latestVersion: latestVersion,
+			}
+			d.report.URL = d.url
+
+			jsonOutput := false
+			if output, _ := cmd.Flags().GetString("output"); output == helper.OutputJSON {
+				jsonOutput = true
+			} else {
+				fmt.Fprintf(out, "Running diagnostics for HBD host: %s\n", d.url)
+			}
+
+			// Print each check as it finishes, some of them can take a while
+			for _, check := range []func() doctorCheck{d.checkConfig, d.checkDNS, d.checkTCP, d.checkTLS, d.checkHealth, d.checkCredentials, d.checkToken, d.checkClock, d.checkVersion} {
+				result := check()
+				d.report.Checks = append(d.report.Checks, result)
+				if !jsonOutput {
+					printDoctorCheck(out, result)
+				}
+			}
+
+			counts := map[string]int{}
+			for _, check := range d.report.Checks {
+				counts[check.Status]++
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(out)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(d.report); err != nil {
+					return helper.NewError("Error encoding report", err)
+				}
+			} else {
+				fmt.Fprintf(out, "%d passed, %d warning(s), %d failed, %d skipped.\n", counts[checkPass], counts[checkWarn], counts[checkFail], counts[checkSkip])
+			}
+
+			if counts[checkFail] > 0 {
+				return helper.NewErrorStr("Error running diagnostics", fmt.Sprintf("%d check(s) failed", counts[checkFail]))
+			}
+
+			return nil
+		},
+	}
+
+	// Add flags to the doctor command
+	doctorCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	doctorCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
+	doctorCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	doctorCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
+	doctorCmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "Timeout for each network check")
+
+	return doctorCmd
+}
+
+// printDoctorCheck prints a line of the checklist, with the status in color on terminals
+func printDoctorCheck(w io.Writer, check doctorCheck) {
+	colors := map[string]*color.Color{
+		checkPass: color.New(color.FgGreen, color.Bold),
+		checkWarn: color.New(color.FgYellow, color.Bold),
+		checkFail: color.New(color.FgRed, color.Bold),
+		checkSkip: color.New(color.Faint),
+	}
+
+	fmt.Fprintf(w, "%s  %-11s  %s\n", colors[check.Status].Sprintf("%-4s", check.Status), check.Name, check.Message)
+}
+
+// source tells where the value of a flag comes from
+func (d *doctor) source(flag, env string) string {
+	switch {
+	case d.cmd.Flags().Changed(flag):
+		return "flag"
+	case os.Getenv(env) != "":
+		return env
+	default:
+		return "default"
+	}
+}
+
+// address is the host and port to connect to, with the default port of the scheme if none is set,
+// or the path of the Unix socket
+func (d *doctor) address() string {
+	if d.socket != "" {
+		return d.socket
+	}
+
+	port := d.port
+	if port == "" {
+		port = "80"
+		if d.ssl {
+			port = "443"
+		}
+	}
+
+	return net.JoinHostPort(d.host, port)
+}
+
+func (d *doctor) checkConfig() doctorCheck {
+	check := doctorCheck{Name: "config", Status: checkPass}
+	check.Message = fmt.Sprintf("%s (host from %s, port from %s, ssl from %s), credentials in %s",
+		d.url, d.source("host", "HBD_HOST"), d.source("port", "HBD_PORT"), d.source("ssl", "HBD_SSL"), helper.InterpretTildeAsHomeDir(d.credsPath))
+	if helper.BaseURL() != "" {
+		check.Message = fmt.Sprintf("%s (from %s), credentials in %s", d.url, d.source("url", "HBD_URL"), helper.InterpretTildeAsHomeDir(d.credsPath))
+		if d.socket != "" {
+			check.Message = fmt.Sprintf("%s through the Unix socket %s (from %s), credentials in %s", d.url, d.socket, d.source("url", "HBD_URL"), helper.InterpretTildeAsHomeDir(d.credsPath))
+		}
+	}
+
+	if d.host == "" || d.host == "0.0.0.0" {
+		check.Status = checkWarn
+		check.Message += ", set --host or HBD_HOST to the HBD service"
+	}
+
+	return check
+}
+
+func (d *doctor) checkDNS() doctorCheck {
+	check := doctorCheck{Name: "dns"}
+
+	if d.socket != "" {
+		d.resolved = true
+		check.Status = checkPass
+		check.Message = fmt.Sprintf("connecting through the Unix socket %s", d.socket)
+		return check
+	}
+	if net.ParseIP(d.host) != nil {
+		d.resolved = true
+		check.Status = checkPass
+		check.Message = fmt.Sprintf("%s is an IP address", d.host)
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, d.host)
+	if err != nil {
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("could not resolve %s: %v", d.host, err)
+		return check
+	}
+
+	d.resolved = true
+	check.Status = checkPass
+	check.Message = fmt.Sprintf("%s resolves to %s", d.host, strings.Join(addrs, ", "))
+	return check
+}
+
+func (d *doctor) checkTCP() doctorCheck {
+	check := doctorCheck{Name: "tcp"}
+
+	if !d.resolved {
+		check.Status = checkSkip
+		check.Message = "the host could not be resolved"
+		return check
+	}
+
+	start := time.Now()
+	network := "tcp"
+	if d.socket != "" {
+		network = "unix"
+	}
+	conn, err := net.DialTimeout(network, d.address(), d.timeout)
+	if err != nil {
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("could not connect to %s: %v", d.address(), err)
+		return check
+	}
+	conn.Close()
+	d.reachable = true
+
+	check.Status = checkPass
+	check.Message = fmt.Sprintf("connected to %s in %s", d.address(), time.Since(start).Round(time.Microsecond))
+	return check
+}
+
+func (d *doctor) checkTLS() doctorCheck {
+	check := doctorCheck{Name: "tls", Status: checkSkip}
+
+	switch {
+	case !d.ssl:
+		check.Message = "--ssl is not set"
+		return check
+	case !d.reachable:
+		check.Message = "the host is not reachable"
+		return check
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: d.timeout}, "tcp", d.address(), helper.TLSConfigForHost(d.host, true))
+	if err != nil {
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("TLS handshake failed: %v", err)
+		return check
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	cert := state.PeerCertificates[0]
+	left := time.Until(cert.NotAfter)
+
+	check.Status = checkPass
+	check.Message = fmt.Sprintf("%s, certificate for %s valid until %s (%d days left)",
+		tls.VersionName(state.Version), cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"), int(left.Hours()/24))
+	if left < certExpiryWarning {
+		check.Status = checkWarn
+	}
+
+	return check
+}
+
+func (d *doctor) checkHealth() doctorCheck {
+	check := doctorCheck{Name: "health"}
+
+	probe, err := api.ProbeHealth(d.url, d.timeout)
+	d.probe = probe
+	if err != nil {
+		check.Status = checkFail
+		check.Message = err.Error()
+		return check
+	}
+
+	check.Status = checkPass
+	check.Message = fmt.Sprintf("%s in %s", probe.Ready.Status, probe.Latency.Round(time.Microsecond))
+	switch {
+	case probe.Ready.Status != "ready":
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("the service is %q instead of ready", probe.Ready.Status)
+	case probe.Latency > slowHealthWarning:
+		check.Status = checkWarn
+		check.Message += ", the service is slow"
+	}
+
+	return check
+}
+
+func (d *doctor) checkCredentials() doctorCheck {
+	check := doctorCheck{Name: "credentials", Status: checkPass}
+	path := helper.InterpretTildeAsHomeDir(d.credsPath)
+
+	if runtime.GOOS == "windows" {
+		check.Status = checkSkip
+		check.Message = "file permissions are not checked on Windows"
+		return check
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("no credentials file at %s", path)
+		return check
+	}
+	if err != nil {
+		check.Status = checkFail
+		check.Message = err.Error()
+		return check
+	}
+
+	// The token gives full access to the account, nobody else should be able to read it
+	var problems []string
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		problems = append(problems, fmt.Sprintf("%s has permissions %04o, run 'chmod 600 %s'", path, perm, path))
+	}
+	if dirInfo, err := os.Stat(filepath.Dir(path)); err == nil && dirInfo.Mode().Perm()&0077 != 0 {
+		problems = append(problems, fmt.Sprintf("%s has permissions %04o, run 'chmod 700 %s'", filepath.Dir(path), dirInfo.Mode().Perm(), filepath.Dir(path)))
+	}
+
+	if len(problems) > 0 {
+		check.Status = checkWarn
+		check.Message = strings.Join(problems, "; ")
+		return check
+	}
+
+	check.Message = fmt.Sprintf("%s is only readable by you", path)
+	return check
+}
+
+func (d *doctor) checkToken() doctorCheck {
+	check := doctorCheck{Name: "token"}
+
+	token, err := helper.LoadToken(d.credsPath)
+	if errors.Is(err, helper.ErrNotLoggedIn) {
+		check.Status = checkWarn
+		check.Message = helper.ErrNotLoggedIn.Error()
+		return check
+	}
+	if err != nil {
+		check.Status = checkFail
+		check.Message = err.Error()
+		return check
+	}
+
+	expiry, err := helper.TokenExpiry(token)
+	if err != nil {
+		check.Status = checkFail
+		check.Message = err.Error()
+		return check
+	}
+
+	left := time.Until(expiry)
+	switch {
+	case expiry.IsZero():
+		check.Message = "the token never expires"
+	case left <= 0:
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("the token expired at %s, log in again with 'hbd auth login'", expiry.Local().Format("2006-01-02 15:04:05"))
+		return check
+	default:
+		check.Message = fmt.Sprintf("the token expires at %s (in %s)", expiry.Local().Format("2006-01-02 15:04:05"), left.Round(time.Minute))
+	}
+
+	// Only the server can tell if the token is valid
+	if d.probe == nil {
+		check.Status = checkWarn
+		check.Message += ", but the server could not be asked whether it's valid"
+		return check
+	}
+	if _, err := api.GetUserData(d.url, token); err != nil {
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("the server rejected the token: %v", err)
+		return check
+	}
+
+	check.Status = checkPass
+	if !expiry.IsZero() && left < tokenExpiryWarning {
+		check.Status = checkWarn
+	}
+	check.Message += ", accepted by the server"
+	return check
+}
+
+func (d *doctor) checkClock() doctorCheck {
+	check := doctorCheck{Name: "clock", Status: checkSkip}
+
+	switch {
+	case d.probe == nil:
+		check.Message = "the server could not be reached"
+		return check
+	case d.probe.ServerTime.IsZero():
+		check.Message = "the server didn't send a Date header"
+		return check
+	}
+
+	// The Date header has a one second resolution, anything within the latency is noise
+	skew := time.Since(d.probe.ServerTime) - d.probe.Latency/2
+	abs := skew.Abs().Round(time.Second)
+	direction := "ahead of"
+	if skew < 0 {
+		direction = "behind"
+	}
+
+	check.Status = checkPass
+	check.Message = fmt.Sprintf("the local clock is %s %s the server", abs, direction)
+	switch {
+	case abs > clockSkewFailure:
+		check.Status = checkFail
+	case abs > clockSkewWarning:
+		check.Status = checkWarn
+	}
+
+	return check
+}
+
+func (d *doctor) checkVersion() doctorCheck {
+	check := doctorCheck{Name: "version"}
+
+	// Development builds are never a release
+	if d.version == "dev" {
+		check.Status = checkSkip
+		check.Message = "development build, not compared to the releases"
+		return check
+	}
+
+	latest, err := d.latestVersion()
+	if err == nil && latest == "" {
+		err = errors.New("no release found")
+	}
+	if err != nil {
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("could not check for the latest version: %v", err)
+		return check
+	}
+
+	if fmt.Sprintf("v%s", d.version) != latest {
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("%s is available (current: %s), download it: https://github.com/dreth/hbd-cli/releases/latest", latest, d.version)
+		return check
+	}
+
+	check.Status = checkPass
+	check.Message = fmt.Sprintf("%s is the latest version", d.version)
+	return check
+}
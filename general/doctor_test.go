@@ -0,0 +1,232 @@
+package general
+
+import (
+	"errors"
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// newTestDoctor returns a doctor for the service at the URL, logged in with the token when it isn't empty
+func newTestDoctor(t *testing.T, rawURL, token string) *doctor {
+	t.Helper()
+	t.Setenv("HBD_TOKEN", "")
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("url.Parse(%s) error = %v", rawURL, err)
+	}
+
+	credsPath := filepath.Join(t.TempDir(), "credentials", u.Hostname())
+	if token != "" {
+		if err := helper.SaveCredentials(credsPath, &helper.Credentials{Token: token}); err != nil {
+			t.Fatalf("SaveCredentials() error = %v", err)
+		}
+	}
+
+	return &doctor{
+		cmd:       &cobra.Command{},
+		host:      u.Hostname(),
+		port:      u.Port(),
+		ssl:       u.Scheme == "https",
+		credsPath: credsPath,
+		timeout:   2 * time.Second,
+		url:       rawURL,
+		version:   "1.2.0",
+		latestVersion: func() (string, error) {
+			return "v1.2.0", nil
+		},
+	}
+}
+
+// runDoctorChecks runs the checks in the order of the doctor command and returns their statuses by name
+func runDoctorChecks(d *doctor) map[string]doctorCheck {
+	checks := map[string]doctorCheck{}
+	for _, check := range []func() doctorCheck{d.checkConfig, d.checkDNS, d.checkTCP, d.checkTLS, d.checkHealth, d.checkCredentials, d.checkToken, d.checkClock, d.checkVersion} {
+		result := check()
+		checks[result.Name] = result
+	}
+	return checks
+}
+
+// longLivedToken logs in to the test server again, the token of newTestServer expires within the warning threshold
+func longLivedToken(t *testing.T, url string) string {
+	t.Helper()
+	login, err := api.Login(url, structs.LoginRequest{Email: "dev@hbd.lotiguere.com", Password: "secret"}, 720)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	return login.Token
+}
+
+func TestDoctorHealthyService(t *testing.T) {
+	url, _ := newTestServer(t, func(h http.Handler) http.Handler { return h })
+	d := newTestDoctor(t, url, longLivedToken(t, url))
+
+	checks := runDoctorChecks(d)
+	want := map[string]string{
+		"config":      checkPass,
+		"dns":         checkPass,
+		"tcp":         checkPass,
+		"tls":         checkSkip,
+		"health":      checkPass,
+		"credentials": checkPass,
+		"token":       checkPass,
+		"clock":       checkPass,
+		"version":     checkPass,
+	}
+	for name, status := range want {
+		if checks[name].Status != status {
+			t.Errorf("check %s = %s (%s), want %s", name, checks[name].Status, checks[name].Message, status)
+		}
+	}
+}
+
+func TestDoctorUnreachableService(t *testing.T) {
+	_, token := newTestServer(t, func(h http.Handler) http.Handler { return h })
+
+	// Nothing listens on the port once the server is closed
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	d := newTestDoctor(t, server.URL, token)
+
+	checks := runDoctorChecks(d)
+	want := map[string]string{
+		"tcp":    checkFail,
+		"health": checkFail,
+		"token":  checkWarn,
+		"clock":  checkSkip,
+	}
+	for name, status := range want {
+		if checks[name].Status != status {
+			t.Errorf("check %s = %s (%s), want %s", name, checks[name].Status, checks[name].Message, status)
+		}
+	}
+}
+
+func TestDoctorCheckHealth(t *testing.T) {
+	// The server answers, but isn't ready yet
+	notReady := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/health" {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"status":"starting"}`))
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+	url, _ := newTestServer(t, notReady)
+	d := newTestDoctor(t, url, "")
+
+	check := d.checkHealth()
+	if check.Status != checkWarn || !strings.Contains(check.Message, "starting") {
+		t.Errorf("checkHealth() = %+v, want a warning about the service starting", check)
+	}
+}
+
+func TestDoctorCheckTLS(t *testing.T) {
+	// The certificate of the test server isn't trusted
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	d := newTestDoctor(t, server.URL, "")
+	d.reachable = true
+	if check := d.checkTLS(); check.Status != checkFail {
+		t.Errorf("checkTLS() with an untrusted certificate = %+v, want a failure", check)
+	}
+
+	d.ssl = false
+	if check := d.checkTLS(); check.Status != checkSkip {
+		t.Errorf("checkTLS() without --ssl = %+v, want it skipped", check)
+	}
+}
+
+func TestDoctorCheckToken(t *testing.T) {
+	url, token := newTestServer(t, func(h http.Handler) http.Handler { return h })
+	// The token of another server has a signature this one doesn't accept
+	_, otherToken := newTestServer(t, func(h http.Handler) http.Handler { return h })
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"accepted", longLivedToken(t, url), checkPass},
+		{"expires soon", token, checkWarn},
+		{"not logged in", "", checkWarn},
+		{"not a JWT", "not-a-jwt", checkFail},
+		{"rejected by the server", otherToken, checkFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDoctor(t, url, tt.token)
+			d.checkHealth()
+			if check := d.checkToken(); check.Status != tt.want {
+				t.Errorf("checkToken() = %+v, want %s", check, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoctorCheckCredentials(t *testing.T) {
+	d := newTestDoctor(t, "http://127.0.0.1:8417", "token")
+	if check := d.checkCredentials(); check.Status != checkPass {
+		t.Errorf("checkCredentials() = %+v, want a pass", check)
+	}
+
+	if err := os.Chmod(d.credsPath, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if check := d.checkCredentials(); check.Status != checkWarn || !strings.Contains(check.Message, "chmod 600") {
+		t.Errorf("checkCredentials() of a readable file = %+v, want a warning to chmod it", check)
+	}
+
+	d.credsPath = filepath.Join(t.TempDir(), "missing")
+	if check := d.checkCredentials(); check.Status != checkWarn {
+		t.Errorf("checkCredentials() of a missing file = %+v, want a warning", check)
+	}
+}
+
+func TestDoctorCheckVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		latest  string
+		err     error
+		want    string
+	}{
+		{"latest", "1.2.0", "v1.2.0", nil, checkPass},
+		{"outdated", "1.1.0", "v1.2.0", nil, checkWarn},
+		{"no release", "1.2.0", "", nil, checkWarn},
+		{"lookup failed", "1.2.0", "", errors.New("rate limited"), checkWarn},
+		{"development build", "dev", "v1.2.0", nil, checkSkip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			d := &doctor{version: tt.version, latestVersion: func() (string, error) {
+				called = true
+				return tt.latest, tt.err
+			}}
+			if check := d.checkVersion(); check.Status != tt.want {
+				t.Errorf("checkVersion() = %+v, want %s", check, tt.want)
+			}
+			if tt.version == "dev" && called {
+				t.Error("checkVersion() looked up the latest release for a development build")
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package general
+
+import (
+	"fmt"
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// upcomingWindows are the number of days the hbd_birthdays_upcoming metric is exposed for
+var upcomingWindows = []int{7, 30}
+
+// exporter probes the HBD service periodically and serves the results of the last probe as metrics
+type exporter struct {
+	url           string
+	credsPath     string
+	leapDayPolicy string
+	version       string
+	timeout       time.Duration
+
+	mu            sync.Mutex
+	up            bool
+	probeDuration time.Duration
+	// loggedIn is whether there were credentials for the last probe, account metrics are only exposed then
+	loggedIn  bool
+	accountUp bool
+	birthdays int
+	upcoming  map[int]int
+}
+
+func Exporter(version string) *cobra.Command {
+	var host string
+	var port string
+	var ssl bool
+	var credsPath string
+	var listen string
+	var interval time.Duration
+	var timeout time.Duration
+	var leapDayPolicy string
+
+	var exporterCmd = &cobra.Command{
+		Use:   "exporter",
+		Short: "Serve Prometheus metrics about an HBD service",
+		Long: `The exporter command probes the health of the HBD service every --interval and serves the
+results on /metrics in the Prometheus text format.
+
+Metrics:
+  hbd_up - 1 if the last health probe succeeded and the service was ready, 0 otherwise.
+  hbd_probe_duration_seconds - How long the last health probe took.
+  hbd_cli_info - Always 1, with the version of the CLI as a label.
+
+When there are credentials for the host, the account is also checked:
+  hbd_account_up - 1 if the user data of the account could be retrieved, 0 otherwise.
+  hbd_birthdays_total - Number of birthdays in the account.
+  hbd_birthdays_upcoming - Number of birthdays in the next 7 and 30 days, by the days label.
+
+Environment variables:
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service.
+  HBD_SSL - Use SSL (https) for the connection.
+  HBD_CREDS_PATH - Path to the credentials file.
+  HBD_TOKEN - JWT token used when there's no credentials file.
+  HBD_LEAP_DAY_POLICY - When to count February 29 birthdays in non-leap years (feb28 or mar1). Defaults to feb28.
+
+Example usage:
+  hbd-cli exporter --listen=":9105" --host="hbd.lotiguere.com" --ssl --interval=30s
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+
+			// Validate the flags
+			if interval <= 0 {
+				return helper.WithExitCode(helper.ExitValidation, helper.NewErrorStr("Error validating flags", "--interval must be greater than 0"))
+			}
+			if err := helper.ValidateLeapDayPolicy(leapDayPolicy); err != nil {
+				return helper.WithExitCode(helper.ExitUsage, helper.NewError("Error validating flags", err))
+			}
+
+			e := &exporter{
+				url:           helper.GenUrl(host, port, ssl),
+				credsPath:     filepath.Join(credsPath, host),
+				leapDayPolicy: leapDayPolicy,
+				version:       version,
+				timeout:       timeout,
+			}
+
+			// Probe once before serving, so the first scrape has data
+			e.probe()
+			go func() {
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for range ticker.C {
+					e.probe()
+				}
+			}()
+
+			mux := http.NewServeMux()
+			mux.Handle("GET /metrics", e)
+			mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, `<html><head><title>HBD exporter</title></head><body><h1>HBD exporter</h1><p><a href="/metrics">Metrics</a></p></body></html>`)
+			})
+
+			fmt.Fprintf(out, "Exporting metrics of HBD host %s on http://%s/metrics, probing every %s\n", e.url, listen, interval)
+
+			// Serve until interrupted
+			return helper.NewError("Error running exporter", http.ListenAndServe(listen, mux))
+		},
+	}
+
+	// Add flags to the exporter command
+	exporterCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	exporterCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
+	exporterCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	exporterCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
+	exporterCmd.Flags().StringVar(&listen, "listen", ":9105", "Address to serve the metrics on")
+	exporterCmd.Flags().DurationVar(&interval, "interval", 30*time.Second, "Time between probes")
+	exporterCmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for each probe")
+	exporterCmd.Flags().StringVar(&leapDayPolicy, "leap-day-policy", helper.DefaultLeapDayPolicy(), "When to count February 29 birthdays in non-leap years (feb28 or mar1)")
+
+	// Return the exporter command
+	return exporterCmd
+}
+
+// probe checks the health of the service and, with credentials, the account
+func (e *exporter) probe() {
+	probe, err := api.ProbeHealth(e.url, e.timeout)
+	up := err == nil && probe.Ready.Status == "ready"
+	var probeDuration time.Duration
+	if probe != nil {
+		probeDuration = probe.Latency
+	}
+
+	// Credentials are loaded on every probe, so logging in doesn't need a restart
+	token, tokenErr := helper.LoadToken(e.credsPath)
+	loggedIn := tokenErr == nil
+	accountUp := false
+	birthdays := 0
+	upcoming := map[int]int{}
+	// The account is only checked when the service is up, with the same timeout as the health probe
+	// so a service hanging after a good health check doesn't block the next probes
+	if loggedIn && up {
+		userData, err := api.GetUserDataWithTimeout(e.url, token, e.timeout)
+		if err == nil {
+			accountUp = true
+			birthdays = len(userData.Birthdays)
+			now := time.Now()
+			for _, birthday := range userData.Birthdays {
+				birth, err := helper.ParseBirthdayDate(birthday.Date)
+				if err != nil {
+					continue
+				}
+				daysLeft := helper.DaysUntil(birth, now, e.leapDayPolicy)
+				for _, days := range upcomingWindows {
+					if daysLeft <= days {
+						upcoming[days]++
+					}
+				}
+			}
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.up = up
+	e.probeDuration = probeDuration
+	e.loggedIn = loggedIn
+	e.accountUp = accountUp
+	e.birthdays = birthdays
+	e.upcoming = upcoming
+}
+
+// ServeHTTP serves the metrics of the last probe
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", helper.MetricsContentType)
+	e.writeMetrics(w)
+}
+
+// writeMetrics writes the metrics of the last probe in the Prometheus text format
+func (e *exporter) writeMetrics(w io.Writer) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	metrics := []helper.Metric{
+		{Name: "hbd_up", Help: "Whether the last health probe succeeded and the HBD service was ready.", Type: "gauge", Samples: []helper.Sample{{Value: boolToFloat(e.up)}}},
+		{Name: "hbd_probe_duration_seconds", Help: "How long the last health probe took.", Type: "gauge", Samples: []helper.Sample{{Value: e.probeDuration.Seconds()}}},
+		{Name: "hbd_cli_info", Help: "Version of the hbd CLI running the exporter.", Type: "gauge", Samples: []helper.Sample{{Labels: map[string]string{"version": e.version}, Value: 1}}},
+	}
+
+	if e.loggedIn {
+		metrics = append(metrics, helper.Metric{Name: "hbd_account_up", Help: "Whether the user data of the account could be retrieved in the last probe.", Type: "gauge", Samples: []helper.Sample{{Value: boolToFloat(e.accountUp)}}})
+	}
+	if e.accountUp {
+		upcoming := helper.Metric{Name: "hbd_birthdays_upcoming", Help: "Number of birthdays in the next days, by the days label.", Type: "gauge"}
+		for _, days := range upcomingWindows {
+			upcoming.Samples = append(upcoming.Samples, helper.Sample{Labels: map[string]string{"days": strconv.Itoa(days)}, Value: float64(e.upcoming[days])})
+		}
+		metrics = append(metrics,
+			helper.Metric{Name: "hbd_birthdays_total", Help: "Number of birthdays in the account.", Type: "gauge", Samples: []helper.Sample{{Value: float64(e.birthdays)}}},
+			upcoming,
+		)
+	}
+
+	return helper.WriteMetrics(w, metrics)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,158 @@
+package general
+
+import (
+	"bytes"
+	"hbd-cli/api"
+	"hbd-cli/devserver"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer starts a dev server with an account holding two birthdays, wrapped by the handler
+// returned by wrap, and returns its URL and the token of the account
+func newTestServer(t *testing.T, wrap func(http.Handler) http.Handler) (string, string) {
+	t.Helper()
+
+	server, err := devserver.New(devserver.Options{})
+	if err != nil {
+		t.Fatalf("devserver.New() error = %v", err)
+	}
+	ts := httptest.NewServer(wrap(server))
+	t.Cleanup(ts.Close)
+
+	registered, err := api.Register(ts.URL, structs.RegisterRequest{
+		Email:             "dev@hbd.lotiguere.com",
+		Password:          "secret",
+		ReminderTime:      "09:00",
+		Timezone:          "Europe/Madrid",
+		TelegramBotAPIKey: "270485614:AAHfiqksKZ8WmR2zSjiQ7jd8Eud81ggE3e-3",
+		TelegramUserID:    "123456789",
+	}, 1)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	for _, birthday := range []structs.BirthdayNameDateAdd{{Name: "John Doe", Date: "1990-01-01"}, {Name: "Jane Doe", Date: "1991-07-15"}} {
+		if _, err := api.AddBirthday(ts.URL, registered.Token, birthday); err != nil {
+			t.Fatalf("AddBirthday() error = %v", err)
+		}
+	}
+
+	return ts.URL, registered.Token
+}
+
+// newTestExporter returns an exporter for the URL, logged in with the token when it isn't empty
+func newTestExporter(t *testing.T, url, token string) *exporter {
+	t.Helper()
+	t.Setenv("HBD_TOKEN", "")
+
+	credsPath := filepath.Join(t.TempDir(), "credentials")
+	if token != "" {
+		if err := helper.SaveCredentials(credsPath, &helper.Credentials{Token: token}); err != nil {
+			t.Fatalf("SaveCredentials() error = %v", err)
+		}
+	}
+
+	return &exporter{url: url, credsPath: credsPath, leapDayPolicy: helper.LeapDayFeb28, version: "test", timeout: time.Second}
+}
+
+// scrape probes the service and returns the metrics written
+func scrape(t *testing.T, e *exporter) string {
+	t.Helper()
+	e.probe()
+
+	var b bytes.Buffer
+	if err := e.writeMetrics(&b); err != nil {
+		t.Fatalf("writeMetrics() error = %v", err)
+	}
+	return b.String()
+}
+
+func TestExporterProbe(t *testing.T) {
+	passthrough := func(h http.Handler) http.Handler { return h }
+
+	// Blocks every request for the user data until the test ends
+	done := make(chan struct{})
+	hangingMe := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/me" {
+				<-done
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	url, token := newTestServer(t, passthrough)
+	hangingURL, hangingToken := newTestServer(t, hangingMe)
+	// Registered after the server so the hanging requests end before it's closed
+	t.Cleanup(func() { close(done) })
+
+	tests := []struct {
+		name    string
+		url     string
+		token   string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:  "logged in",
+			url:   url,
+			token: token,
+			want:  []string{"hbd_up 1\n", "hbd_account_up 1\n", "hbd_birthdays_total 2\n", `hbd_birthdays_upcoming{days="30"}`, `hbd_cli_info{version="test"} 1`},
+		},
+		{
+			name:    "not logged in",
+			url:     url,
+			want:    []string{"hbd_up 1\n"},
+			notWant: []string{"hbd_account_up", "hbd_birthdays_total"},
+		},
+		{
+			name:    "invalid token",
+			url:     url,
+			token:   "invalid",
+			want:    []string{"hbd_up 1\n", "hbd_account_up 0\n"},
+			notWant: []string{"hbd_birthdays_total"},
+		},
+		{
+			name:    "user data hangs",
+			url:     hangingURL,
+			token:   hangingToken,
+			want:    []string{"hbd_up 1\n", "hbd_account_up 0\n"},
+			notWant: []string{"hbd_birthdays_total"},
+		},
+		{
+			name:    "service down",
+			url:     "http://127.0.0.1:1",
+			token:   token,
+			want:    []string{"hbd_up 0\n", "hbd_account_up 0\n"},
+			notWant: []string{"hbd_birthdays_total"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			metrics := scrape(t, newTestExporter(t, tt.url, tt.token))
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("probe() took %s, want it bounded by the timeout", elapsed)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(metrics, want) {
+					t.Errorf("metrics don't contain %q:\n%s", want, metrics)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(metrics, notWant) {
+					t.Errorf("metrics contain %q:\n%s", notWant, metrics)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package general
+
+import (
+	"fmt"
+	"hbd-cli/helper"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+	"github.com/spf13/pflag"
+)
+
+// Formats of the generated docs
+const (
+	docsMan      = "man"
+	docsMarkdown = "markdown"
+	docsReST     = "rest"
+)
+
+// docsDescription replaces the splash screen of the root command, which is meant for terminals
+const docsDescription = `hbd is a CLI tool to manage birthday reminders using an HBD backend.
+
+Don't have an HBD backend? You can self-host your own instance: https://github.com/dreth/hbd
+or use ours: https://hbd.lotiguere.com
+
+If you encounter any issues or have any suggestions, feel free to open an issue: https://github.com/dreth/hbd-cli`
+
+// docsSectionHeader matches the headers of the sections in the long help texts, e.g. "Environment variables:"
+var docsSectionHeader = regexp.MustCompile(`^[A-Z][A-Za-z ]*:$`)
+
+// docsListItem matches the items of the sections that start with a name, e.g. "HBD_HOST - The host for the service."
+var docsListItem = regexp.MustCompile(`^([A-Za-z0-9_./-]+) - (.*)$`)
+
+// GenDocs returns the gen-docs command, newRoot builds the command tree the docs are generated from
+func GenDocs(version string, newRoot func() *cobra.Command) *cobra.Command {
+	var format string
+	var dir string
+
+	var genDocsCmd = &cobra.Command{
+		Use:    "gen-docs",
+		Short:  "Generate the reference docs of every command",
+		Hidden: true,
+		Long: `The gen-docs command generates a page for every command of the CLI in --dir,
+as man pages, markdown or reStructuredText (rest).
+
+The sections of the help texts, like the environment variables and the examples,
+are turned into lists and code blocks of the format.
+
+Example usage:
+  hbd-cli gen-docs --format=man --dir="out/man1"
+  hbd-cli gen-docs --format=markdown --dir="out/"
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			if format != docsMan && format != docsMarkdown && format != docsReST {
+				return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error validating flags", fmt.Sprintf("unknown format %q, must be man, markdown or rest", format)))
+			}
+
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return helper.NewError("Error creating docs directory", err)
+			}
+
+			// The docs are generated from a new tree built without the HBD_* variables, so the flag defaults
+			// are the same for everyone, with the help texts converted to the format
+			restoreEnv := blankDocsEnv()
+			root := newRoot()
+			restoreEnv()
+			root.Short = "Manage birthday reminders using an HBD backend"
+			root.Long = docsDescription
+			root.DisableAutoGenTag = true
+			convertLongHelp(root, format)
+
+			var err error
+			switch format {
+			case docsMan:
+				err = doc.GenManTree(root, &doc.GenManHeader{Title: "HBD", Section: "1", Source: "hbd-cli " + version, Manual: "hbd manual"}, dir)
+			case docsMarkdown:
+				err = doc.GenMarkdownTree(root, dir)
+			case docsReST:
+				err = doc.GenReSTTree(root, dir)
+			}
+			if err != nil {
+				return helper.NewError("Error generating docs", err)
+			}
+
+			fmt.Fprintf(out, "Generated %s docs in %s\n", format, dir)
+
+			return nil
+		},
+	}
+
+	// Add flags to the gen-docs command
+	genDocsCmd.Flags().StringVar(&format, "format", docsMarkdown, "Format of the docs (man, markdown or rest)")
+	genDocsCmd.Flags().StringVar(&dir, "dir", "docs", "Directory to write the docs to")
+
+	// Return the gen-docs command
+	return genDocsCmd
+}
+
+// blankDocsEnv sets the HBD_* variables of the environment and of the .env file to empty values, which
+// the flag defaults read as unset, and returns a function restoring them
+func blankDocsEnv() func() {
+	names := map[string]bool{}
+	for _, env := range os.Environ() {
+		if name, _, _ := strings.Cut(env, "="); strings.HasPrefix(name, "HBD_") {
+			names[name] = true
+		}
+	}
+	if dotenv, err := godotenv.Read(); err == nil {
+		for name := range dotenv {
+			if strings.HasPrefix(name, "HBD_") {
+				names[name] = true
+			}
+		}
+	}
+
+	previous := map[string]string{}
+	for name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			previous[name] = value
+		}
+		os.Setenv(name, "")
+	}
+
+	return func() {
+		for name := range names {
+			if value, ok := previous[name]; ok {
+				os.Setenv(name, value)
+			} else {
+				os.Unsetenv(name)
+			}
+		}
+	}
+}
+
+// convertLongHelp converts the long help texts of cmd and its subcommands to the docs format,
+// man pages are generated from markdown. The home directory in the flag defaults is shown as ~,
+// so the docs are the same for everyone.
+func convertLongHelp(cmd *cobra.Command, format string) {
+	cmd.Long = docsLong(cmd.Long, format)
+
+	if home, err := os.UserHomeDir(); err == nil {
+		replaceHome := func(flag *pflag.Flag) {
+			flag.DefValue = strings.Replace(flag.DefValue, home, "~", 1)
+		}
+		cmd.LocalFlags().VisitAll(replaceHome)
+		cmd.PersistentFlags().VisitAll(replaceHome)
+	}
+
+	for _, sub := range cmd.Commands() {
+		convertLongHelp(sub, format)
+	}
+}
+
+// docsLong converts a long help text, written for terminals, to markdown or reStructuredText.
+// The indented lines after a header become a code block for examples, and a list otherwise.
+func docsLong(long, format string) string {
+	lines := strings.Split(strings.TrimSpace(long), "\n")
+
+	var b strings.Builder
+	header := ""
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+
+		if !strings.HasPrefix(line, "  ") {
+			header = line
+			standalone := i == 0 || strings.TrimSpace(lines[i-1]) == ""
+			if standalone && docsSectionHeader.MatchString(line) {
+				line = "**" + line + "**"
+			}
+			if format != docsReST {
+				line = strings.ReplaceAll(line, "<", `\<`)
+			}
+			b.WriteString(line + "\n")
+			continue
+		}
+
+		// Collect the indented block
+		var block []string
+		for ; i < len(lines) && strings.HasPrefix(lines[i], "  "); i++ {
+			block = append(block, strings.TrimSpace(lines[i]))
+		}
+		i--
+
+		b.WriteString("\n")
+		if strings.HasPrefix(header, "Example") {
+			writeDocsCode(&b, block, format)
+		} else {
+			writeDocsList(&b, block, format)
+		}
+		if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+			b.WriteString("\n")
+		}
+	}
+
+	return strings.TrimSpace(b.String()) + "\n"
+}
+
+func writeDocsCode(b *strings.Builder, block []string, format string) {
+	if format == docsReST {
+		b.WriteString("::\n\n")
+		for _, line := range block {
+			b.WriteString("    " + line + "\n")
+		}
+		return
+	}
+
+	b.WriteString("```sh\n" + strings.Join(block, "\n") + "\n```\n")
+}
+
+func writeDocsList(b *strings.Builder, block []string, format string) {
+	literal := "`"
+	if format == docsReST {
+		literal = "``"
+	}
+
+	for _, item := range block {
+		item = strings.TrimPrefix(item, "- ")
+		if format != docsReST {
+			item = strings.ReplaceAll(item, "<", `\<`)
+		}
+		if match := docsListItem.FindStringSubmatch(item); match != nil {
+			item = literal + match[1] + literal + " - " + match[2]
+		}
+		b.WriteString("- " + item + "\n")
+	}
+}
@@ -0,0 +1,67 @@
+package general
+
+import (
+	"flag"
+	"hbd-cli/helper"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/docs")
+
+func TestDocsLong(t *testing.T) {
+	// The backup help has a header with a <placeholder>, an environment variables list and examples
+	long := Backup().Long
+
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{docsMarkdown, "backup.md"},
+		{docsMan, "backup.man.md"},
+		{docsReST, "backup.rst"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got := docsLong(long, tt.format)
+
+			path := filepath.Join("testdata", "docs", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("missing golden file, run the tests with -update: %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("docsLong() differs from %s, run the tests with -update if the change is intended\n--- got\n%s--- want\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+func TestBlankDocsEnv(t *testing.T) {
+	t.Setenv("HBD_HOST", "hbd.example.com")
+	t.Setenv("HBD_PORT", "8418")
+	t.Setenv("HBD_SSL", "true")
+
+	restore := blankDocsEnv()
+	host, port, ssl := helper.DefaultHost(), helper.DefaultPort(), helper.DefaultSSL()
+	restore()
+
+	if host != "0.0.0.0" || port != "" || ssl {
+		t.Errorf("defaults without the environment = %q, %q, %v, want the built-in ones", host, port, ssl)
+	}
+	if got := os.Getenv("HBD_HOST"); got != "hbd.example.com" {
+		t.Errorf("HBD_HOST after restoring = %q, want hbd.example.com", got)
+	}
+	if helper.DefaultPort() != "8418" || !helper.DefaultSSL() {
+		t.Error("the environment wasn't restored")
+	}
+}
@@ -1,42 +1,136 @@
 package general
 
 import (
+	"context"
 	"fmt"
 	"hbd-cli/api"
 	"hbd-cli/helper"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+// Health states, named after the Nagios plugin states
+const (
+	healthOK       = "OK"
+	healthWarning  = "WARNING"
+	healthCritical = "CRITICAL"
+	healthUnknown  = "UNKNOWN"
+)
+
+// nagiosExitCodes are the exit codes of the Nagios plugin API for each state
+var nagiosExitCodes = map[string]int{
+	healthOK:       0,
+	healthWarning:  1,
+	healthCritical: 2,
+	healthUnknown:  3,
+}
+
+// latencyWindowSize is how many of the latest latencies the watch percentiles are computed over
+const latencyWindowSize = 100
+
 func HealthCheck() *cobra.Command {
 	var host string
 	var port string
 	var ssl bool
+	var watch bool
+	var interval time.Duration
+	var nagios bool
+	var warning time.Duration
+	var critical time.Duration
+	var timeout time.Duration
 
 	var healthCheckCmd = &cobra.Command{
 		Use:   "health",
 		Short: "Health check the HBD service",
 		Long: `The health command checks the readiness of the HBD service.
 
+With --watch, the service is checked every --interval and a line is printed each time its state changes,
+along with the latency percentiles of the latest checks. Stop it with Ctrl+C.
+
+With --nagios, a single check is printed in the Nagios plugin format, with the latency as perfdata,
+and the command exits with 0 (OK), 1 (WARNING) or 2 (CRITICAL), or 3 (UNKNOWN) for invalid flags.
+
+The state is CRITICAL when the service can't be reached or isn't ready, or when the latency is over --critical,
+WARNING when it's over --warning, and OK otherwise.
+
 Environment variables:
   HBD_HOST - The host for the service. Defaults to 0.0.0.0.
-  HBD_PORT - The port for the service. 
+  HBD_PORT - The port for the service.
   HBD_SSL - Use SSL (https) for the connection.
 
 Example usage:
   hbd-cli health --host="hbd.lotiguere.com" --ssl --port="8080"
+  hbd-cli health --host="hbd.lotiguere.com" --ssl --watch --interval=30s
+  hbd-cli health --host="hbd.lotiguere.com" --ssl --nagios --warning=500ms --critical=2s
 		`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Validate the flags
+			var invalid string
+			code := helper.ExitValidation
+			switch {
+			case watch && nagios:
+				invalid = "--watch and --nagios can't be used together"
+				code = helper.ExitUsage
+			case interval <= 0 || warning <= 0 || critical <= 0:
+				invalid = "--interval, --warning and --critical must be greater than 0"
+			case warning > critical:
+				invalid = "--warning must not be greater than --critical"
+			}
+			if invalid != "" {
+				if nagios {
+					return nagiosUnknown(out, invalid)
+				}
+				return helper.WithExitCode(code, helper.NewErrorStr("Error validating flags", invalid))
+			}
+
 			// Create the URL
 			url := helper.GenUrl(host, port, ssl)
-			fmt.Printf("Performing a health check on HBD host: %s\n", url)
+
+			if nagios {
+				probe, err := api.ProbeHealth(url, timeout)
+				state, message := classifyHealth(probe, err, warning, critical)
+
+				fmt.Fprintf(out, "HBD %s - %s", state, message)
+				if err == nil {
+					fmt.Fprintf(out, " | latency=%.6fs;%.6f;%.6f;0;", probe.Latency.Seconds(), warning.Seconds(), critical.Seconds())
+				}
+				fmt.Fprintln(out)
+
+				if state != healthOK {
+					return helper.NewSilentError(nagiosExitCodes[state], fmt.Errorf("the service is %s", state))
+				}
+				return nil
+			}
+
+			if watch {
+				ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+				defer stop()
+
+				fmt.Fprintf(out, "Watching HBD host: %s every %s, press Ctrl+C to stop\n", url, interval)
+				watchHealth(ctx, out, url, interval, warning, critical, timeout)
+				return nil
+			}
+
+			fmt.Fprintf(out, "Performing a health check on HBD host: %s\n", url)
 
 			// Make the request
 			health, err := api.CheckHealth(url)
-			helper.HandleErrorExit("Error checking health", err)
+			if err != nil {
+				return helper.NewError("Error checking health", err)
+			}
 
 			// Print the health status
-			fmt.Printf("Service health status: %s\n", health.Status)
+			fmt.Fprintf(out, "Service health status: %s\n", health.Status)
+
+			return nil
 		},
 	}
 
@@ -44,7 +138,115 @@ Example usage:
 	healthCheckCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
 	healthCheckCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
 	healthCheckCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	healthCheckCmd.Flags().BoolVar(&watch, "watch", false, "Keep checking the service and print its state changes")
+	healthCheckCmd.Flags().DurationVar(&interval, "interval", 30*time.Second, "Time between checks with --watch")
+	healthCheckCmd.Flags().BoolVar(&nagios, "nagios", false, "Print the result in the Nagios plugin format and exit with its codes")
+	healthCheckCmd.Flags().DurationVar(&warning, "warning", time.Second, "Latency over which the state is WARNING")
+	healthCheckCmd.Flags().DurationVar(&critical, "critical", 5*time.Second, "Latency over which the state is CRITICAL")
+	healthCheckCmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for each check with --watch and --nagios")
+
+	// Invalid flags are UNKNOWN for Nagios, as long as --nagios was parsed before them
+	healthCheckCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		if nagios {
+			return nagiosUnknown(cmd.OutOrStdout(), err.Error())
+		}
+		return err
+	})
 
 	// Return the health check command
 	return healthCheckCmd
 }
+
+// nagiosUnknown prints an UNKNOWN result for a usage error and returns the error exiting with its code
+func nagiosUnknown(out io.Writer, message string) error {
+	fmt.Fprintf(out, "HBD %s - %s\n", healthUnknown, message)
+	return helper.NewSilentError(nagiosExitCodes[healthUnknown], fmt.Errorf("invalid flags: %s", message))
+}
+
+// classifyHealth returns the state of the service from a health probe, and a message describing it
+func classifyHealth(probe *api.HealthProbe, err error, warning, critical time.Duration) (string, string) {
+	if err != nil {
+		return healthCritical, err.Error()
+	}
+
+	latency := probe.Latency.Round(time.Microsecond)
+	switch {
+	case probe.Ready.Status != "ready":
+		return healthCritical, fmt.Sprintf("service status is %q in %s", probe.Ready.Status, latency)
+	case probe.Latency > critical:
+		return healthCritical, fmt.Sprintf("service ready in %s, over %s", latency, critical)
+	case probe.Latency > warning:
+		return healthWarning, fmt.Sprintf("service ready in %s, over %s", latency, warning)
+	default:
+		return healthOK, fmt.Sprintf("service ready in %s", latency)
+	}
+}
+
+// watchHealth checks the service every interval until the context is done, printing the state changes
+func watchHealth(ctx context.Context, out io.Writer, url string, interval, warning, critical, timeout time.Duration) {
+	var latencies latencyWindow
+	counts := map[string]int{}
+	lastState := ""
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		probe, err := api.ProbeHealth(url, timeout)
+		state, message := classifyHealth(probe, err, warning, critical)
+		counts[state]++
+		if err == nil {
+			latencies.add(probe.Latency)
+		}
+
+		if state != lastState {
+			fmt.Fprintf(out, "%s %-8s %s (%s)\n", time.Now().Format("2006-01-02 15:04:05"), state, message, latencies)
+			lastState = state
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(out, "Stopped after %d check(s): %d OK, %d WARNING, %d CRITICAL (%s)\n",
+				counts[healthOK]+counts[healthWarning]+counts[healthCritical], counts[healthOK], counts[healthWarning], counts[healthCritical], latencies)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// latencyWindow keeps the latest latencies to compute rolling percentiles
+type latencyWindow struct {
+	latencies []time.Duration
+}
+
+func (w *latencyWindow) add(latency time.Duration) {
+	w.latencies = append(w.latencies, latency)
+	if len(w.latencies) > latencyWindowSize {
+		w.latencies = w.latencies[1:]
+	}
+}
+
+// percentile returns the nearest-rank percentile p of the latencies, between 0 and 100
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	if len(w.latencies) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration(nil), w.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func (w latencyWindow) String() string {
+	if len(w.latencies) == 0 {
+		return "no latency yet"
+	}
+
+	return fmt.Sprintf("p50 %s, p90 %s, p99 %s over the last %d",
+		w.percentile(50).Round(time.Microsecond), w.percentile(90).Round(time.Microsecond), w.percentile(99).Round(time.Microsecond), len(w.latencies))
+}
@@ -0,0 +1,131 @@
+package general
+
+import (
+	"bytes"
+	"errors"
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyHealth(t *testing.T) {
+	ready := func(latency time.Duration) *api.HealthProbe {
+		return &api.HealthProbe{Ready: structs.Ready{Status: "ready"}, Latency: latency}
+	}
+
+	tests := []struct {
+		name        string
+		probe       *api.HealthProbe
+		err         error
+		wantState   string
+		wantMessage string
+	}{
+		{"ok", ready(100 * time.Millisecond), nil, healthOK, "service ready in 100ms"},
+		{"at warning is ok", ready(time.Second), nil, healthOK, "service ready in 1s"},
+		{"over warning", ready(1500 * time.Millisecond), nil, healthWarning, "service ready in 1.5s, over 1s"},
+		{"at critical is warning", ready(5 * time.Second), nil, healthWarning, "service ready in 5s, over 1s"},
+		{"over critical", ready(6 * time.Second), nil, healthCritical, "service ready in 6s, over 5s"},
+		{"not ready", &api.HealthProbe{Ready: structs.Ready{Status: "starting"}, Latency: time.Millisecond}, nil, healthCritical, `service status is "starting" in 1ms`},
+		{"error", nil, errors.New("connection refused"), healthCritical, "connection refused"},
+		{"error with probe", ready(time.Millisecond), errors.New("status 503"), healthCritical, "status 503"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, message := classifyHealth(tt.probe, tt.err, time.Second, 5*time.Second)
+			if state != tt.wantState || message != tt.wantMessage {
+				t.Errorf("classifyHealth() = %s, %q, want %s, %q", state, message, tt.wantState, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestLatencyWindowPercentile(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		var latencies []time.Duration
+		for _, v := range values {
+			latencies = append(latencies, time.Duration(v)*time.Millisecond)
+		}
+		return latencies
+	}
+
+	tests := []struct {
+		name      string
+		latencies []time.Duration
+		p         float64
+		want      time.Duration
+	}{
+		{"empty", nil, 50, 0},
+		{"single", ms(7), 99, 7 * time.Millisecond},
+		{"p50 of odd count", ms(5, 1, 3, 2, 4), 50, 3 * time.Millisecond},
+		{"p50 of even count", ms(4, 1, 3, 2), 50, 2 * time.Millisecond},
+		{"p90 rounds the rank up", ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11), 90, 10 * time.Millisecond},
+		{"p100 is the max", ms(3, 9, 1), 100, 9 * time.Millisecond},
+		{"p0 is the min", ms(3, 9, 1), 0, 1 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w latencyWindow
+			for _, latency := range tt.latencies {
+				w.add(latency)
+			}
+			if got := w.percentile(tt.p); got != tt.want {
+				t.Errorf("percentile(%v) = %s, want %s", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyWindowEviction(t *testing.T) {
+	var w latencyWindow
+	for i := 1; i <= latencyWindowSize+20; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+
+	if len(w.latencies) != latencyWindowSize {
+		t.Fatalf("window holds %d latencies, want %d", len(w.latencies), latencyWindowSize)
+	}
+	// The 20 oldest were evicted
+	if got := w.percentile(0); got != 21*time.Millisecond {
+		t.Errorf("min latency = %s, want 21ms", got)
+	}
+	if got := w.percentile(100); got != 120*time.Millisecond {
+		t.Errorf("max latency = %s, want 120ms", got)
+	}
+	if got := w.String(); !strings.HasSuffix(got, "over the last 100") {
+		t.Errorf("String() = %q, want it over the last 100", got)
+	}
+}
+
+func TestHealthNagiosUnknown(t *testing.T) {
+	tests := [][]string{
+		{"--nagios", "--warning=3s", "--critical=1s"},
+		{"--nagios", "--interval=0s"},
+		{"--nagios", "--watch"},
+		{"--nagios", "--warning=abc"},
+	}
+
+	for _, args := range tests {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := HealthCheck()
+			cmd.SetArgs(args)
+			cmd.SetOut(&out)
+			cmd.SetErr(&out)
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+
+			err := cmd.Execute()
+			if code := helper.ExitCode(err); code != 3 {
+				t.Errorf("exit code = %d, want 3 (error %v)", code, err)
+			}
+			if !strings.HasPrefix(out.String(), "HBD UNKNOWN - ") {
+				t.Errorf("output = %q, want an UNKNOWN result", out.String())
+			}
+		})
+	}
+}
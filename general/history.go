@@ -0,0 +1,116 @@
+package general
+
+import (
+	"fmt"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+
+	"github.com/spf13/cobra"
+)
+
+func History() *cobra.Command {
+	var host, port string
+	var ssl bool
+	var limit int
+
+	var historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "List the birthday changes made with the CLI",
+		Long: `The history command lists the birthdays added, modified and deleted with the CLI,
+as recorded in the local journal of a given HBD service (default is ~/.hbd/journal, one file per service).
+
+Changes can be reverted with the undo command.
+
+Environment variables:
+  HBD_JOURNAL_PATH - Path to the journal directory.
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service.
+  HBD_SSL - Use SSL (https) for the connection.
+
+Example usage:
+  hbd-cli history --host="hbd.lotiguere.com" --limit=50
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+
+			// Load the journal
+			journalPath := helper.JournalFilePath(helper.GenUrl(host, port, ssl))
+			entries, err := helper.LoadJournal(journalPath)
+			if err != nil {
+				return helper.NewError("Error loading journal", err)
+			}
+
+			if len(entries) == 0 {
+				fmt.Fprintf(out, "No changes recorded in %s\n", journalPath)
+				return nil
+			}
+
+			// Find the entries that have been undone
+			undone := undoneEntries(entries)
+
+			// Print the latest entries, oldest first
+			if limit > 0 && len(entries) > limit {
+				entries = entries[len(entries)-limit:]
+			}
+			for _, entry := range entries {
+				status := ""
+				if undone[entry.Seq] {
+					status = " (undone)"
+				}
+				if entry.Undoes != 0 {
+					status = fmt.Sprintf(" (undo of #%d)", entry.Undoes)
+				}
+				fmt.Fprintf(out, "#%d %s %s%s\n", entry.Seq, entry.Time.Local().Format("2006-01-02 15:04:05"), describeJournalEntry(entry), status)
+			}
+
+			return nil
+		},
+	}
+
+	// Add flags to the history command
+	historyCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	historyCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
+	historyCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	historyCmd.Flags().IntVar(&limit, "limit", 20, "Number of entries to show, 0 shows all of them")
+
+	// Return the history command
+	return historyCmd
+}
+
+// undoneEntries returns the sequence numbers of the entries that have been undone
+func undoneEntries(entries []helper.JournalEntry) map[int]bool {
+	undone := map[int]bool{}
+	for _, entry := range entries {
+		if entry.Undoes != 0 {
+			undone[entry.Undoes] = true
+		}
+	}
+	return undone
+}
+
+// describeJournalEntry returns a one line description of a journal entry
+func describeJournalEntry(entry helper.JournalEntry) string {
+	describe := func(birthday *structs.BirthdayFull) string {
+		if birthday == nil {
+			return "unknown"
+		}
+		if birthday.ID == 0 {
+			return fmt.Sprintf("Name: %s, Date: %s", birthday.Name, birthday.Date)
+		}
+		return fmt.Sprintf("ID: %d, Name: %s, Date: %s", birthday.ID, birthday.Name, birthday.Date)
+	}
+
+	switch entry.Operation {
+	case helper.JournalAdd:
+		return "ADD    " + describe(entry.After)
+	case helper.JournalDelete:
+		return "DELETE " + describe(entry.Before)
+	case helper.JournalModify:
+		return fmt.Sprintf("MODIFY %s -> %s", describe(entry.Before), describe(entry.After))
+	}
+
+	return entry.Operation
+}
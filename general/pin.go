@@ -0,0 +1,117 @@
+package general
+
+import (
+	"crypto/tls"
+	"fmt"
+	"hbd-cli/helper"
+	"net"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func Pin() *cobra.Command {
+	var host string
+	var port string
+	var list bool
+	var remove bool
+	var chain bool
+	var timeout time.Duration
+
+	var pinCmd = &cobra.Command{
+		Use:   "pin",
+		Short: "Pin the public key of an HBD host",
+		Long: `The pin command connects to the HBD host with TLS and saves the SHA-256 hash of the public key
+of its certificate for the host (default is ~/.hbd/pins/<host>).
+
+Every later connection to the host with --ssl fails unless a certificate of its chain has a pinned key,
+even if the certificate is otherwise trusted. Pin the whole chain with --chain to keep working when
+the certificate of the server is renewed with a new key. The --pin-sha256 flag replaces the saved pins
+for a single command.
+
+The handshake is made with the TLS flags (--ca-cert, --tls-server-name, etc.), but without checking
+the pins saved before, so a changed key can be pinned again.
+
+Environment variables:
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service. Defaults to 443.
+  HBD_PINS_PATH - Path to the pins directory.
+
+Example usage:
+  hbd-cli pin --host="hbd.lotiguere.com"
+  hbd-cli pin --host="hbd.lotiguere.com" --list
+  hbd-cli pin --host="hbd.lotiguere.com" --remove
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+
+			if list {
+				pins, err := helper.LoadPins(host)
+				if err != nil {
+					return helper.NewError("Error loading pins", err)
+				}
+				if len(pins) == 0 {
+					fmt.Fprintf(out, "No pins saved for %s\n", host)
+					return nil
+				}
+				for _, pin := range pins {
+					fmt.Fprintln(out, pin)
+				}
+				return nil
+			}
+
+			if remove {
+				if err := helper.SavePins(host, nil); err != nil {
+					return helper.NewError("Error removing pins", err)
+				}
+				fmt.Fprintf(out, "Removed the pins of %s\n", host)
+				return nil
+			}
+
+			if port == "" {
+				port = "443"
+			}
+
+			// Connect without the saved pins, they're the ones being replaced
+			conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", net.JoinHostPort(host, port), helper.TLSConfigForHost(host, false))
+			if err != nil {
+				return helper.WithExitCode(helper.ExitNetwork, helper.NewError("Error connecting to the host", err))
+			}
+			defer conn.Close()
+
+			certs := conn.ConnectionState().PeerCertificates
+			if !chain {
+				certs = certs[:1]
+			}
+
+			pins := make([]string, 0, len(certs))
+			for _, cert := range certs {
+				pin := helper.PublicKeyPin(cert)
+				pins = append(pins, pin)
+				fmt.Fprintf(out, "%s %s\n", pin, cert.Subject.CommonName)
+			}
+
+			if err := helper.SavePins(host, pins); err != nil {
+				return helper.NewError("Error saving pins", err)
+			}
+			fmt.Fprintf(out, "Pinned %d public key(s) for %s\n", len(pins), host)
+
+			return nil
+		},
+	}
+
+	// Add flags to the pin command
+	pinCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	pinCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service, 443 if empty")
+	pinCmd.Flags().BoolVar(&list, "list", false, "List the pins saved for the host")
+	pinCmd.Flags().BoolVar(&remove, "remove", false, "Remove the pins saved for the host")
+	pinCmd.Flags().BoolVar(&chain, "chain", false, "Pin the public keys of the whole certificate chain, not only the server's")
+	pinCmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for the connection")
+	pinCmd.MarkFlagsMutuallyExclusive("list", "remove", "chain")
+
+	// Return the pin command
+	return pinCmd
+}
@@ -0,0 +1,247 @@
+package general
+
+import (
+	"bufio"
+	"fmt"
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func Restore() *cobra.Command {
+	var host, port, credsPath, backupsPath string
+	var ssl, settings, autoConfirm, dryRun bool
+
+	var restoreCmd = &cobra.Command{
+		Use:   "restore <snapshot>",
+		Short: "Restore your account from a local snapshot",
+		Long: `The restore command recreates the birthdays from a snapshot that are missing in
+your account. Birthdays that exist in your account but not in the snapshot are left untouched.
+
+With --settings, the reminder time, timezone and Telegram settings from the snapshot
+are applied as well. A preview of the changes is shown before asking for confirmation.
+
+The snapshot can be a path to a snapshot file, the name of a file in the backups
+directory of the host, or "latest".
+
+Environment variables:
+  HBD_CREDS_PATH - Path to the credentials file.
+  HBD_BACKUPS_PATH - Path to the backups directory.
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service.
+  HBD_SSL - Use SSL (https) for the connection.
+
+Example usage:
+  hbd-cli restore latest --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
+  hbd-cli restore ~/.hbd/backups/hbd.lotiguere.com/hbd-20240801T120000.000000000Z.json --settings --yes
+		`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+
+			// Find and load the snapshot
+			backupsPath = filepath.Join(backupsPath, host)
+			path, err := resolveSnapshot(backupsPath, args[0])
+			if err != nil {
+				return helper.NewError("Error finding snapshot", err)
+			}
+
+			snapshot, err := helper.LoadSnapshot(path)
+			if err != nil {
+				return helper.NewError("Error loading snapshot", err)
+			}
+
+			// Load the token
+			credsPath = filepath.Join(credsPath, host)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
+
+			// Create the URL
+			url := helper.GenUrl(host, port, ssl)
+
+			// Get the current state of the account
+			userData, err := api.GetUserData(url, token)
+			if err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
+
+			// Work out which birthdays are missing
+			existing := map[string]bool{}
+			for _, birthday := range userData.Birthdays {
+				existing[birthdayKey(birthday)] = true
+			}
+			var missing []structs.BirthdayFull
+			for _, birthday := range snapshot.UserData.Birthdays {
+				if !existing[birthdayKey(birthday)] {
+					missing = append(missing, birthday)
+				}
+			}
+
+			// Work out which settings differ
+			saved := snapshot.UserData
+			changes := settingsChanges(userData, &saved)
+			settingsChanged := len(changes) > 0
+
+			// Print the preview
+			fmt.Fprintf(out, "Snapshot taken at %s from %s\n\n", snapshot.CreatedAt.Local().Format("2006-01-02 15:04:05"), snapshot.Host)
+			fmt.Fprintf(out, "Birthdays to recreate (%d):\n", len(missing))
+			for _, birthday := range missing {
+				fmt.Fprintf(out, "+ Name: %s, Date: %s\n", birthday.Name, birthday.Date)
+			}
+			if settings {
+				fmt.Fprintln(out, "\nSettings:")
+				for _, change := range changes {
+					fmt.Fprintf(out, "~ %s\n", change)
+				}
+				if !settingsChanged {
+					fmt.Fprintln(out, "  (no changes)")
+				}
+			}
+
+			if len(missing) == 0 && (!settings || !settingsChanged) {
+				fmt.Fprintln(out, "\nNothing to restore.")
+				return nil
+			}
+			if dryRun {
+				return nil
+			}
+
+			// If autoConfirm is not set, ask for confirmation
+			if !autoConfirm {
+				reader := bufio.NewReader(cmd.InOrStdin())
+				fmt.Fprint(out, "\nApply these changes? Type 'yes' to proceed: ")
+				confirmation, _ := reader.ReadString('\n')
+				confirmation = strings.TrimSpace(confirmation)
+
+				if confirmation != "yes" {
+					fmt.Fprintln(out, "Restore cancelled.")
+					return nil
+				}
+			}
+
+			// Recreate the missing birthdays
+			failed := 0
+			for _, birthday := range missing {
+				added, err := api.AddBirthday(url, token, structs.BirthdayNameDateAdd{Name: birthday.Name, Date: birthday.Date})
+				if err != nil {
+					failed++
+					fmt.Fprintf(out, "FAILED  Name: %s, Date: %s (%v)\n", birthday.Name, birthday.Date, err)
+					continue
+				}
+				fmt.Fprintf(out, "ADDED   Name: %s, Date: %s\n", birthday.Name, birthday.Date)
+
+				// Record the change so it can be undone
+				helper.RecordBirthdayChange(cmd.ErrOrStderr(), url, helper.JournalAdd, nil, added)
+			}
+
+			// Reapply the settings
+			if settings && settingsChanged {
+				_, err := api.ModifyUserWithoutEmail(url, token, structs.ModifyUserRequest{
+					NewReminderTime:      saved.ReminderTime,
+					NewTimezone:          saved.Timezone,
+					NewTelegramBotAPIKey: saved.TelegramBotAPIKey,
+					NewTelegramUserID:    saved.TelegramUserID,
+				})
+				if err != nil {
+					failed++
+					fmt.Fprintf(out, "FAILED  Settings (%v)\n", err)
+				} else {
+					fmt.Fprintln(out, "UPDATED Settings")
+				}
+			}
+
+			if failed > 0 {
+				return helper.NewErrorStr("Error restoring snapshot", fmt.Sprintf("%d change(s) failed", failed))
+			}
+			fmt.Fprintln(out, "Restore completed successfully!")
+
+			return nil
+		},
+	}
+
+	// Add flags to the restore command
+	restoreCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	restoreCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
+	restoreCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	restoreCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
+	restoreCmd.Flags().StringVar(&backupsPath, "backups-path", helper.GetDefaultBackupsPath(), "Path to the backups directory")
+	restoreCmd.Flags().BoolVar(&settings, "settings", false, "Also reapply the account settings from the snapshot")
+	restoreCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the changes, don't apply them")
+	restoreCmd.Flags().BoolVarP(&autoConfirm, "yes", "y", false, "Automatic yes to confirmation prompt")
+
+	// Return the restore command
+	return restoreCmd
+}
+
+// resolveSnapshot turns a snapshot argument into the path of a snapshot file
+func resolveSnapshot(backupsPath, snapshot string) (string, error) {
+	// The latest snapshot of the host
+	if snapshot == "latest" {
+		paths, err := helper.ListSnapshots(backupsPath)
+		if err != nil {
+			return "", err
+		}
+		if len(paths) == 0 {
+			return "", fmt.Errorf("no snapshots found in %s", backupsPath)
+		}
+		return paths[len(paths)-1], nil
+	}
+
+	// A path to a file
+	path := helper.InterpretTildeAsHomeDir(snapshot)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	// A file name in the backups directory of the host
+	path = filepath.Join(helper.InterpretTildeAsHomeDir(backupsPath), snapshot)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	return "", fmt.Errorf("snapshot %s not found", snapshot)
+}
+
+// settingsChanges describes the settings that differ between the current and the saved user data,
+// without showing the Telegram bot API key
+func settingsChanges(current, saved *structs.UserData) []string {
+	var changes []string
+	for _, diff := range []struct {
+		name, current, saved string
+		secret               bool
+	}{
+		{"Reminder Time", current.ReminderTime, saved.ReminderTime, false},
+		{"Timezone", current.Timezone, saved.Timezone, false},
+		{"Telegram Bot API Key", current.TelegramBotAPIKey, saved.TelegramBotAPIKey, true},
+		{"Telegram User ID", current.TelegramUserID, saved.TelegramUserID, false},
+	} {
+		switch {
+		case diff.current == diff.saved:
+		case diff.secret:
+			changes = append(changes, diff.name+": changed")
+		default:
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", diff.name, diff.current, diff.saved))
+		}
+	}
+
+	return changes
+}
+
+// birthdayKey identifies a birthday by its name and date, ignoring its ID
+func birthdayKey(birthday structs.BirthdayFull) string {
+	date := birthday.Date
+	if parsed, err := helper.ParseBirthdayDate(date); err == nil {
+		date = parsed.Format(helper.DateLayout)
+	}
+	return birthday.Name + "\x00" + date
+}
@@ -0,0 +1,82 @@
+package general
+
+import (
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	createdAt := time.Date(2024, time.August, 1, 12, 0, 0, 0, time.UTC)
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path, err := helper.SaveSnapshot(dir, &helper.Snapshot{CreatedAt: createdAt.Add(time.Duration(i) * time.Minute)})
+		if err != nil {
+			t.Fatalf("SaveSnapshot() error = %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	tests := []struct {
+		name     string
+		dir      string
+		snapshot string
+		want     string
+		wantErr  bool
+	}{
+		{"latest", dir, "latest", paths[2], false},
+		{"latest without snapshots", t.TempDir(), "latest", "", true},
+		{"latest without directory", filepath.Join(dir, "missing"), "latest", "", true},
+		{"path", t.TempDir(), paths[1], paths[1], false},
+		{"file name", dir, filepath.Base(paths[0]), paths[0], false},
+		{"unknown file name", dir, "hbd-unknown.json", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSnapshot(tt.dir, tt.snapshot)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveSnapshot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveSnapshot() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettingsChanges(t *testing.T) {
+	current := &structs.UserData{ReminderTime: "08:00", Timezone: "UTC", TelegramBotAPIKey: "123:current", TelegramUserID: "42"}
+
+	tests := []struct {
+		name  string
+		saved structs.UserData
+		want  []string
+	}{
+		{"unchanged", *current, nil},
+		{"reminder time and timezone", structs.UserData{ReminderTime: "09:30", Timezone: "Europe/Madrid", TelegramBotAPIKey: "123:current", TelegramUserID: "42"},
+			[]string{"Reminder Time: 08:00 -> 09:30", "Timezone: UTC -> Europe/Madrid"}},
+		{"telegram settings", structs.UserData{ReminderTime: "08:00", Timezone: "UTC", TelegramBotAPIKey: "123:saved", TelegramUserID: "43"},
+			[]string{"Telegram Bot API Key: changed", "Telegram User ID: 42 -> 43"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := settingsChanges(current, &tt.saved)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("settingsChanges() = %q, want %q", got, tt.want)
+			}
+			for _, change := range got {
+				if strings.Contains(change, "123:") {
+					t.Errorf("settingsChanges() shows the Telegram bot API key in %q", change)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package general
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hbd-cli/helper"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/peterh/liner"
+	"github.com/spf13/cobra"
+)
+
+// RunFunc runs the command tree with args, like a new hbd process, and returns its exit code
+type RunFunc func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+// errUnterminatedQuote is returned when a line ends inside a quoted word
+var errUnterminatedQuote = errors.New("unterminated quote")
+
+func Shell(run RunFunc) *cobra.Command {
+	var historyPath string
+
+	var shellCmd = &cobra.Command{
+		Use:   "shell",
+		Short: "Run hbd commands in an interactive shell",
+		Long: `The shell command reads hbd commands and runs them one after the other, without
+the hbd prefix, e.g. "birthdays list". Arguments with spaces can be quoted like in a shell.
+
+The credentials are only read once and the user data is only retrieved once for all the commands,
+until a command changes it. The history is kept in ~/.hbd/history, except for the lines setting
+a password or a Telegram bot API key, and Tab completes the commands, their flags, the birthday IDs
+and the birthday names.
+
+Type "exit" or press Ctrl+D to leave the shell.
+
+Environment variables:
+  HBD_HISTORY_PATH - Path to the history file.
+
+Example usage:
+  hbd-cli shell
+  hbd> birthdays list
+  hbd> birthdays delete --match="John Doe"
+		`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Keep the token and user data in memory for every command
+			helper.StartSession()
+
+			normalMode, normalErr := liner.TerminalMode()
+			line := liner.NewLiner()
+			defer line.Close()
+			line.SetCtrlCAborts(true)
+			line.SetTabCompletionStyle(liner.TabPrints)
+			line.SetWordCompleter(func(input string, pos int) (string, []string, string) {
+				return completeShellLine(run, input, pos)
+			})
+
+			// Load the history, it doesn't exist the first time
+			historyPath = helper.InterpretTildeAsHomeDir(historyPath)
+			if f, err := os.Open(historyPath); err == nil {
+				line.ReadHistory(f)
+				f.Close()
+			}
+			saveHistory := func() {
+				if err := os.MkdirAll(filepath.Dir(historyPath), 0700); err != nil {
+					return
+				}
+				if f, err := os.OpenFile(historyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); err == nil {
+					line.WriteHistory(f)
+					f.Close()
+				}
+			}
+			defer saveHistory()
+
+			fmt.Fprintln(out, `hbd shell, type "help" for the commands and "exit" to leave`)
+			for {
+				input, err := line.Prompt("hbd> ")
+				if errors.Is(err, liner.ErrPromptAborted) {
+					continue
+				}
+				if err != nil {
+					// Ctrl+D
+					fmt.Fprintln(out)
+					return nil
+				}
+
+				input = strings.TrimSpace(input)
+				if input == "" {
+					continue
+				}
+
+				// Lines with passwords or API keys are left out of the history
+				words, err := splitShellWords(input)
+				if !hasSecretFlag(words) {
+					line.AppendHistory(input)
+				}
+				if err != nil {
+					fmt.Fprintln(cmd.ErrOrStderr(), "Error:", err)
+					continue
+				}
+				// Commands copied from a terminal start with hbd
+				if words[0] == "hbd" {
+					words = words[1:]
+				}
+
+				switch {
+				case len(words) == 0:
+					continue
+				case words[0] == "exit" || words[0] == "quit":
+					return nil
+				case words[0] == "shell":
+					fmt.Fprintln(cmd.ErrOrStderr(), "Error: already in the hbd shell")
+					continue
+				}
+
+				// The terminal is in raw mode for the prompt, commands asking for a confirmation need its normal mode
+				rawMode, rawErr := liner.TerminalMode()
+				if normalErr == nil && rawErr == nil {
+					normalMode.ApplyMode()
+				}
+				run(words, cmd.InOrStdin(), out, cmd.ErrOrStderr())
+				if normalErr == nil && rawErr == nil {
+					rawMode.ApplyMode()
+				}
+				saveHistory()
+			}
+		},
+	}
+
+	// Add flags to the shell command
+	shellCmd.Flags().StringVar(&historyPath, "history-path", helper.GetDefaultHistoryPath(), "Path to the history file")
+
+	// Return the shell command
+	return shellCmd
+}
+
+// secretFlags are the flags whose values are kept out of the history
+var secretFlags = map[string]bool{
+	"password":                 true,
+	"new-password":             true,
+	"telegram-bot-api-key":     true,
+	"new-telegram-bot-api-key": true,
+}
+
+// hasSecretFlag reports whether the words of a line set one of the secret flags
+func hasSecretFlag(words []string) bool {
+	for _, word := range words {
+		name, ok := strings.CutPrefix(word, "--")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, "=")
+		if secretFlags[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// completeShellLine completes the word under the cursor with the completions of the command tree
+func completeShellLine(run RunFunc, input string, pos int) (string, []string, string) {
+	head, tail := input[:pos], input[pos:]
+
+	words, err := splitShellWords(head)
+	toComplete := ""
+	if len(words) > 0 && (err != nil || !strings.HasSuffix(head, " ")) {
+		toComplete = words[len(words)-1]
+		words = words[:len(words)-1]
+		head = head[:lastWordStart(head)]
+	}
+	if len(words) > 0 && words[0] == "hbd" {
+		words = words[1:]
+	}
+
+	// Cobra's completion command prints a completion per line, then the directive
+	var stdout bytes.Buffer
+	run(append(append([]string{cobra.ShellCompRequestCmd}, words...), toComplete), nil, &stdout, io.Discard)
+
+	// The values of --flag=value are completed without the flag
+	flagPrefix := ""
+	if strings.HasPrefix(toComplete, "-") {
+		if i := strings.Index(toComplete, "="); i >= 0 {
+			flagPrefix = toComplete[:i+1]
+		}
+	}
+
+	var completions []string
+	noSpace := false
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if directive, ok := strings.CutPrefix(text, ":"); ok {
+			d, _ := strconv.Atoi(directive)
+			noSpace = cobra.ShellCompDirective(d)&cobra.ShellCompDirectiveNoSpace != 0
+			break
+		}
+
+		completion, _, _ := strings.Cut(text, "\t")
+		completion = flagPrefix + completion
+		if strings.ContainsAny(completion, " \"'\\") {
+			completion = strconv.Quote(completion)
+		}
+		completions = append(completions, completion)
+	}
+
+	// The directive comes last, so the spaces are added once it's known
+	if !noSpace {
+		for i := range completions {
+			completions[i] += " "
+		}
+	}
+
+	return head, completions, tail
+}
+
+// lastWordStart returns where the last word of a line starts, including its opening quote
+func lastWordStart(line string) int {
+	start := 0
+	var quote rune
+	escaped := false
+	inWord := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			inWord = false
+			continue
+		}
+		if !inWord {
+			start = i
+			inWord = true
+		}
+	}
+
+	return start
+}
+
+// splitShellWords splits a line into words like a shell: words are separated by spaces, quotes
+// group them and backslashes escape the next character, except in single quotes.
+// The words read before an unterminated quote are returned along with the error.
+func splitShellWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord := false
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+	if quote != 0 {
+		return words, errUnterminatedQuote
+	}
+
+	return words, nil
+}
@@ -0,0 +1,204 @@
+package general
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"spaces only", "  \t ", nil, false},
+		{"words", "birthdays list  --output json", []string{"birthdays", "list", "--output", "json"}, false},
+		{"double quotes", `birthdays add --name "John Doe"`, []string{"birthdays", "add", "--name", "John Doe"}, false},
+		{"single quotes", `birthdays add --name 'John Doe'`, []string{"birthdays", "add", "--name", "John Doe"}, false},
+		{"quotes inside a word", `--name="John Doe"`, []string{"--name=John Doe"}, false},
+		{"empty quotes", `--name ""`, []string{"--name", ""}, false},
+		{"escaped space", `John\ Doe`, []string{"John Doe"}, false},
+		{"escaped quote in double quotes", `"say \"hi\""`, []string{`say "hi"`}, false},
+		{"backslash in single quotes", `'a\b'`, []string{`a\b`}, false},
+		{"other quote in quotes", `"it's" 'a "b"'`, []string{"it's", `a "b"`}, false},
+		{"unterminated double quote", `add --name "John`, []string{"add", "--name", "John"}, true},
+		{"unterminated single quote", `add --name 'John Doe`, []string{"add", "--name", "John Doe"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitShellWords(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitShellWords(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitShellWords(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasSecretFlag(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"birthdays list", false},
+		{`birthdays add --name="John Doe" --date=1990-01-01`, false},
+		{"auth login --email=dev@hbd.lotiguere.com --password=secret", true},
+		{"hbd auth login --email dev@hbd.lotiguere.com --password secret", true},
+		{`auth login --password "secret`, true},
+		{"auth register --email=dev@hbd.lotiguere.com --telegram-bot-api-key=123:abc", true},
+		{"auth modify-user --new-password=secret", true},
+		{"auth modify-user --new-telegram-bot-api-key 123:abc", true},
+		{"auth modify-user --token-duration=24", false},
+		{`birthdays add --name=--password --date=1990-01-01`, false},
+	}
+
+	for _, tt := range tests {
+		words, _ := splitShellWords(tt.line)
+		if got := hasSecretFlag(words); got != tt.want {
+			t.Errorf("hasSecretFlag(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLastWordStart(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{"", 0},
+		{"birthdays", 0},
+		{"birthdays li", 10},
+		{"birthdays ", 0},
+		{`add --name "John Do`, 11},
+		{`add --name 'John Do`, 11},
+		{`add --name John\ Do`, 11},
+		{`add --name="John Do`, 4},
+	}
+
+	for _, tt := range tests {
+		if got := lastWordStart(tt.line); got != tt.want {
+			t.Errorf("lastWordStart(%q) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteShellLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		pos      int
+		output   string
+		wantArgs []string
+		wantHead string
+		want     []string
+		wantTail string
+	}{
+		{
+			name:     "command",
+			input:    "birthdays del",
+			pos:      -1,
+			output:   "delete\tDelete one or more birthdays\n:4\n",
+			wantArgs: []string{"__complete", "birthdays", "del"},
+			wantHead: "birthdays ",
+			want:     []string{"delete "},
+		},
+		{
+			name:     "new word",
+			input:    "birthdays ",
+			pos:      -1,
+			output:   "add\nlist\n:4\n",
+			wantArgs: []string{"__complete", "birthdays", ""},
+			wantHead: "birthdays ",
+			want:     []string{"add ", "list "},
+		},
+		{
+			name:     "hbd prefix",
+			input:    "hbd birthdays li",
+			pos:      -1,
+			output:   "list\n:4\n",
+			wantArgs: []string{"__complete", "birthdays", "li"},
+			wantHead: "hbd birthdays ",
+			want:     []string{"list "},
+		},
+		{
+			name:     "flag value",
+			input:    "birthdays delete --id=1",
+			pos:      -1,
+			output:   "1\tJohn Doe (2000-02-29)\n12\tMax Power (1985-07-14)\n:4\n",
+			wantArgs: []string{"__complete", "birthdays", "delete", "--id=1"},
+			wantHead: "birthdays delete ",
+			want:     []string{"--id=1 ", "--id=12 "},
+		},
+		{
+			name:     "flag value with a space is quoted",
+			input:    "birthdays delete --match=Jo",
+			pos:      -1,
+			output:   "John Doe\n:4\n",
+			wantArgs: []string{"__complete", "birthdays", "delete", "--match=Jo"},
+			wantHead: "birthdays delete ",
+			want:     []string{`"--match=John Doe" `},
+		},
+		{
+			name:     "unterminated quote",
+			input:    `birthdays delete --match "Jo`,
+			pos:      -1,
+			output:   "John Doe\n:4\n",
+			wantArgs: []string{"__complete", "birthdays", "delete", "--match", "Jo"},
+			wantHead: "birthdays delete --match ",
+			want:     []string{`"John Doe" `},
+		},
+		{
+			name:     "no space directive",
+			input:    "birthdays delete --id=1,",
+			pos:      -1,
+			output:   "1,2\n:6\n",
+			wantArgs: []string{"__complete", "birthdays", "delete", "--id=1,"},
+			wantHead: "birthdays delete ",
+			want:     []string{"--id=1,2"},
+		},
+		{
+			name:     "cursor in the middle",
+			input:    "birthdays li --output json",
+			pos:      12,
+			output:   "list\n:4\n",
+			wantArgs: []string{"__complete", "birthdays", "li"},
+			wantHead: "birthdays ",
+			want:     []string{"list "},
+			wantTail: " --output json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotArgs []string
+			run := func(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+				gotArgs = args
+				fmt.Fprint(stdout, tt.output)
+				return 0
+			}
+
+			pos := tt.pos
+			if pos < 0 {
+				pos = len(tt.input)
+			}
+			head, completions, tail := completeShellLine(run, tt.input, pos)
+
+			if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("completion ran with %q, want %q", gotArgs, tt.wantArgs)
+			}
+			if head != tt.wantHead || tail != tt.wantTail {
+				t.Errorf("completeShellLine() head, tail = %q, %q, want %q, %q", head, tail, tt.wantHead, tt.wantTail)
+			}
+			if !reflect.DeepEqual(completions, tt.want) {
+				t.Errorf("completeShellLine() completions = %q, want %q", completions, tt.want)
+			}
+		})
+	}
+}
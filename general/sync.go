@@ -0,0 +1,303 @@
+package general
+
+import (
+	"fmt"
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+func Sync() *cobra.Command {
+	var host, port, credsPath string
+	var drop []int
+	var ssl, list, force bool
+
+	var syncCmd = &cobra.Command{
+		Use:   "sync",
+		Short: "Replay the birthday changes queued while offline",
+		Long: `The sync command replays, in order, the birthday changes that were queued because
+the HBD service could not be reached (default is ~/.hbd/queue, one file per service).
+
+Before replaying a change, it is checked against the current state of the server.
+Changes that conflict are reported and left in the queue for manual resolution:
+  - adding a birthday that already exists with the same name and date
+  - modifying a birthday that was deleted on the server
+  - modifying or deleting a birthday that was modified on the server in the meantime
+
+Use --list to review the queue, --drop to discard changes and --force to apply
+conflicting changes anyway.
+
+Environment variables:
+  HBD_CREDS_PATH - Path to the credentials file.
+  HBD_QUEUE_PATH - Path to the queue directory.
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service.
+  HBD_SSL - Use SSL (https) for the connection.
+
+Example usage:
+  hbd-cli sync --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
+  hbd-cli sync --list
+  hbd-cli sync --drop=2,3
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+
+			// Create the URL
+			url := helper.GenUrl(host, port, ssl)
+
+			// Load the queue
+			queuePath := helper.QueueFilePath(url)
+			queue, err := helper.LoadQueue(queuePath)
+			if err != nil {
+				return helper.NewError("Error loading queue", err)
+			}
+
+			// List the queued changes
+			if list {
+				if len(queue) == 0 {
+					fmt.Fprintln(out, "No queued changes.")
+					return nil
+				}
+				for i, op := range queue {
+					fmt.Fprintf(out, "#%d %s %s\n", i+1, op.QueuedAt.Local().Format("2006-01-02 15:04:05"), describeQueuedOperation(op))
+				}
+				return nil
+			}
+
+			// Discard queued changes
+			if len(drop) > 0 {
+				dropped := map[int]bool{}
+				for _, n := range drop {
+					if n < 1 || n > len(queue) {
+						return helper.WithExitCode(helper.ExitNotFound, helper.NewErrorStr("Error dropping changes", fmt.Sprintf("no queued change #%d", n)))
+					}
+					dropped[n] = true
+				}
+
+				var remaining []helper.QueuedOperation
+				for i, op := range queue {
+					if dropped[i+1] {
+						fmt.Fprintf(out, "DROPPED #%d %s\n", i+1, describeQueuedOperation(op))
+						continue
+					}
+					remaining = append(remaining, op)
+				}
+				if err := helper.SaveQueue(queuePath, remaining); err != nil {
+					return helper.NewError("Error saving queue", err)
+				}
+				return nil
+			}
+
+			if len(queue) == 0 {
+				fmt.Fprintln(out, "Nothing to sync.")
+				return nil
+			}
+
+			// Load the token
+			credsPath = filepath.Join(credsPath, host)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
+
+			// Get the current state of the server to detect conflicts
+			userData, err := api.GetUserData(url, token)
+			if err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
+
+			current := map[int64]structs.BirthdayFull{}
+			for _, birthday := range userData.Birthdays {
+				current[birthday.ID] = birthday
+			}
+
+			// Replay the changes in order. Later changes of a birthday were queued against the cached
+			// data, so they are checked against what the changes replayed before them left instead
+			var remaining []helper.QueuedOperation
+			replayed := map[int64]structs.BirthdayFull{}
+			synced := 0
+			for i, op := range queue {
+				conflict, skip := queuedConflict(op, current, replayed)
+				if skip != "" {
+					fmt.Fprintf(out, "SKIPPED  #%d %s (%s)\n", i+1, describeQueuedOperation(op), skip)
+					continue
+				}
+				if conflict != "" && !force {
+					fmt.Fprintf(out, "CONFLICT #%d %s (%s)\n", i+1, describeQueuedOperation(op), conflict)
+					remaining = append(remaining, op)
+					continue
+				}
+
+				// Stop if the server became unreachable, keeping the rest of the queue
+				err := replayQueuedOperation(cmd.ErrOrStderr(), url, token, op, current, replayed)
+				if api.IsNetworkError(err) {
+					fmt.Fprintf(out, "FAILED   #%d %s (%v)\n", i+1, describeQueuedOperation(op), err)
+					remaining = append(remaining, queue[i:]...)
+					break
+				}
+				if err != nil {
+					fmt.Fprintf(out, "FAILED   #%d %s (%v)\n", i+1, describeQueuedOperation(op), err)
+					remaining = append(remaining, op)
+					continue
+				}
+
+				synced++
+				fmt.Fprintf(out, "SYNCED   #%d %s\n", i+1, describeQueuedOperation(op))
+			}
+
+			// Keep what couldn't be synced
+			if err := helper.SaveQueue(queuePath, remaining); err != nil {
+				return helper.NewError("Error saving queue", err)
+			}
+			fmt.Fprintf(out, "%d of %d queued change(s) synced.\n", synced, len(queue))
+
+			if len(remaining) > 0 {
+				return helper.NewErrorStr("Error syncing", fmt.Sprintf("%d change(s) left in the queue, review them with --list and resolve them with --drop or --force", len(remaining)))
+			}
+
+			return nil
+		},
+	}
+
+	// Add flags to the sync command
+	syncCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	syncCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
+	syncCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	syncCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
+	syncCmd.Flags().BoolVar(&list, "list", false, "List the queued changes instead of syncing them")
+	syncCmd.Flags().IntSliceVar(&drop, "drop", nil, "Discard the queued changes with these numbers, as shown by --list")
+	syncCmd.Flags().BoolVar(&force, "force", false, "Apply conflicting changes anyway, overwriting the server")
+
+	// Return the sync command
+	return syncCmd
+}
+
+// describeQueuedOperation returns a one line description of a queued change
+func describeQueuedOperation(op helper.QueuedOperation) string {
+	return describeJournalEntry(helper.JournalEntry{Operation: op.Operation, Before: op.Before, After: op.After})
+}
+
+// queuedConflict checks a queued change against the current birthdays on the server. It returns why
+// the change conflicts, or why it can be skipped because it's already applied. The birthdays in replayed,
+// as left by the changes of the queue replayed before, replace what the CLI knew when queueing.
+func queuedConflict(op helper.QueuedOperation, current, replayed map[int64]structs.BirthdayFull) (conflict, skip string) {
+	// changed reports whether the birthday on the server differs from what the CLI knew when queueing
+	changed := func(known *structs.BirthdayFull, server structs.BirthdayFull) bool {
+		if birthday, ok := replayed[server.ID]; ok {
+			known = &birthday
+		}
+		return known != nil && known.Name != "" && (known.Name != server.Name || !sameDate(known.Date, server.Date))
+	}
+
+	switch op.Operation {
+	case helper.JournalAdd:
+		if op.After == nil {
+			return "the queued change has no birthday to add", ""
+		}
+		for _, birthday := range sortedBirthdays(current) {
+			if birthday.Name == op.After.Name && sameDate(birthday.Date, op.After.Date) {
+				return fmt.Sprintf("a birthday with the same name and date already exists with ID %d", birthday.ID), ""
+			}
+		}
+
+	case helper.JournalModify:
+		if op.After == nil {
+			return "the queued change has no birthday to modify", ""
+		}
+		server, ok := current[op.After.ID]
+		if !ok {
+			return "the birthday was deleted on the server", ""
+		}
+		if changed(op.Before, server) {
+			return fmt.Sprintf("the birthday was modified on the server to Name: %s, Date: %s", server.Name, server.Date), ""
+		}
+
+	case helper.JournalDelete:
+		if op.Before == nil {
+			return "the queued change has no birthday to delete", ""
+		}
+		server, ok := current[op.Before.ID]
+		if !ok {
+			return "", "already deleted on the server"
+		}
+		if changed(op.Before, server) {
+			return fmt.Sprintf("the birthday was modified on the server to Name: %s, Date: %s", server.Name, server.Date), ""
+		}
+	}
+
+	return "", ""
+}
+
+// replayQueuedOperation sends a queued change to the API, keeping the current and replayed birthdays and the
+// journal up to date. Failures to record the change in the journal are warned about on errOut.
+func replayQueuedOperation(errOut io.Writer, url, token string, op helper.QueuedOperation, current, replayed map[int64]structs.BirthdayFull) error {
+	if (op.Operation == helper.JournalAdd || op.Operation == helper.JournalModify) && op.After == nil ||
+		op.Operation == helper.JournalDelete && op.Before == nil {
+		return fmt.Errorf("the queued change has no birthday to %s", op.Operation)
+	}
+
+	switch op.Operation {
+	case helper.JournalAdd:
+		added, err := api.AddBirthday(url, token, structs.BirthdayNameDateAdd{Name: op.After.Name, Date: op.After.Date})
+		if err != nil {
+			return err
+		}
+		current[added.ID] = *added
+		replayed[added.ID] = *added
+		helper.RecordBirthdayChange(errOut, url, helper.JournalAdd, nil, added)
+
+	case helper.JournalModify:
+		before, ok := current[op.After.ID]
+		if !ok {
+			return fmt.Errorf("the birthday was deleted on the server")
+		}
+		if _, err := api.ModifyBirthday(url, token, structs.BirthdayNameDateModify(*op.After)); err != nil {
+			return err
+		}
+		current[op.After.ID] = *op.After
+		replayed[op.After.ID] = *op.After
+		helper.RecordBirthdayChange(errOut, url, helper.JournalModify, &before, op.After)
+
+	case helper.JournalDelete:
+		before := current[op.Before.ID]
+		if _, err := api.DeleteBirthday(url, token, structs.BirthdayNameDateModify{ID: op.Before.ID}); err != nil {
+			return err
+		}
+		delete(current, op.Before.ID)
+		delete(replayed, op.Before.ID)
+		helper.RecordBirthdayChange(errOut, url, helper.JournalDelete, &before, nil)
+
+	default:
+		return fmt.Errorf("unknown operation %q", op.Operation)
+	}
+
+	return nil
+}
+
+// sortedBirthdays returns the birthdays ordered by ID
+func sortedBirthdays(birthdays map[int64]structs.BirthdayFull) []structs.BirthdayFull {
+	sorted := make([]structs.BirthdayFull, 0, len(birthdays))
+	for _, birthday := range birthdays {
+		sorted = append(sorted, birthday)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
+
+// sameDate compares two birthday dates, ignoring any time part
+func sameDate(a, b string) bool {
+	da, errA := helper.ParseBirthdayDate(a)
+	db, errB := helper.ParseBirthdayDate(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return da.Equal(db)
+}
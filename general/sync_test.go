@@ -0,0 +1,121 @@
+package general
+
+import (
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueuedConflict(t *testing.T) {
+	john := structs.BirthdayFull{ID: 1, Name: "John Doe", Date: "1990-01-01"}
+	johnny := structs.BirthdayFull{ID: 1, Name: "Johnny Doe", Date: "1990-01-01"}
+	jane := structs.BirthdayFull{ID: 2, Name: "Jane Doe", Date: "1991-07-15"}
+	current := map[int64]structs.BirthdayFull{john.ID: john, jane.ID: jane}
+	// John modified to Johnny by an earlier change of the queue
+	replayed := map[int64]structs.BirthdayFull{johnny.ID: johnny}
+	afterReplay := map[int64]structs.BirthdayFull{johnny.ID: johnny, jane.ID: jane}
+
+	ptr := func(b structs.BirthdayFull) *structs.BirthdayFull { return &b }
+
+	tests := []struct {
+		name         string
+		op           helper.QueuedOperation
+		current      map[int64]structs.BirthdayFull
+		replayed     map[int64]structs.BirthdayFull
+		wantConflict bool
+		wantSkip     bool
+	}{
+		{"add", helper.QueuedOperation{Operation: helper.JournalAdd, After: &structs.BirthdayFull{Name: "Max Power", Date: "1985-07-14"}}, current, nil, false, false},
+		{"add existing", helper.QueuedOperation{Operation: helper.JournalAdd, After: &structs.BirthdayFull{Name: "Jane Doe", Date: "1991-07-15T00:00:00Z"}}, current, nil, true, false},
+		{"modify", helper.QueuedOperation{Operation: helper.JournalModify, Before: ptr(john), After: ptr(johnny)}, current, nil, false, false},
+		{"modify deleted on the server", helper.QueuedOperation{Operation: helper.JournalModify, Before: ptr(john), After: &structs.BirthdayFull{ID: 3, Name: "Nobody", Date: "2000-01-01"}}, current, nil, true, false},
+		{"modify modified on the server", helper.QueuedOperation{Operation: helper.JournalModify, Before: ptr(john), After: ptr(johnny)}, afterReplay, nil, true, false},
+		{"modify without before", helper.QueuedOperation{Operation: helper.JournalModify, After: ptr(johnny)}, current, nil, false, false},
+		{"second modify after a replayed one", helper.QueuedOperation{Operation: helper.JournalModify, Before: ptr(john), After: &structs.BirthdayFull{ID: 1, Name: "Jon Doe", Date: "1990-01-01"}}, afterReplay, replayed, false, false},
+		{"delete after a replayed modify", helper.QueuedOperation{Operation: helper.JournalDelete, Before: ptr(john)}, afterReplay, replayed, false, false},
+		{"delete", helper.QueuedOperation{Operation: helper.JournalDelete, Before: ptr(jane)}, current, nil, false, false},
+		{"delete already deleted", helper.QueuedOperation{Operation: helper.JournalDelete, Before: &structs.BirthdayFull{ID: 3}}, current, nil, false, true},
+		{"delete modified on the server", helper.QueuedOperation{Operation: helper.JournalDelete, Before: ptr(john)}, afterReplay, nil, true, false},
+		{"corrupt add", helper.QueuedOperation{Operation: helper.JournalAdd}, current, nil, true, false},
+		{"corrupt modify", helper.QueuedOperation{Operation: helper.JournalModify, Before: ptr(john)}, current, nil, true, false},
+		{"corrupt delete", helper.QueuedOperation{Operation: helper.JournalDelete}, current, nil, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflict, skip := queuedConflict(tt.op, tt.current, tt.replayed)
+			if (conflict != "") != tt.wantConflict || (skip != "") != tt.wantSkip {
+				t.Errorf("queuedConflict() = %q, %q, want conflict %v, skip %v", conflict, skip, tt.wantConflict, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestReplayQueuedOperation(t *testing.T) {
+	t.Setenv("HBD_JOURNAL_PATH", filepath.Join(t.TempDir(), "journal"))
+	url, token := newTestServer(t, func(h http.Handler) http.Handler { return h })
+
+	userData, err := api.GetUserData(url, token)
+	if err != nil {
+		t.Fatalf("GetUserData() error = %v", err)
+	}
+	current := map[int64]structs.BirthdayFull{}
+	for _, birthday := range userData.Birthdays {
+		current[birthday.ID] = birthday
+	}
+	john := userData.Birthdays[0]
+	replayed := map[int64]structs.BirthdayFull{}
+
+	// The changes made offline to the same birthday, queued against the same cached data
+	queue := []helper.QueuedOperation{
+		{Operation: helper.JournalAdd, After: &structs.BirthdayFull{Name: "Max Power", Date: "1985-07-14"}},
+		{Operation: helper.JournalModify, Before: &john, After: &structs.BirthdayFull{ID: john.ID, Name: "Johnny Doe", Date: john.Date}},
+		{Operation: helper.JournalModify, Before: &john, After: &structs.BirthdayFull{ID: john.ID, Name: "Jon Doe", Date: john.Date}},
+		{Operation: helper.JournalDelete, Before: &john},
+	}
+	for i, op := range queue {
+		if conflict, skip := queuedConflict(op, current, replayed); conflict != "" || skip != "" {
+			t.Fatalf("queuedConflict() of change #%d = %q, %q, want none", i+1, conflict, skip)
+		}
+		if err := replayQueuedOperation(io.Discard, url, token, op, current, replayed); err != nil {
+			t.Fatalf("replayQueuedOperation() of change #%d error = %v", i+1, err)
+		}
+	}
+
+	// The server and the current birthdays agree
+	userData, err = api.GetUserData(url, token)
+	if err != nil {
+		t.Fatalf("GetUserData() error = %v", err)
+	}
+	if len(userData.Birthdays) != len(current) {
+		t.Fatalf("server has %d birthdays, current has %d", len(userData.Birthdays), len(current))
+	}
+	for _, birthday := range userData.Birthdays {
+		if birthday.ID == john.ID {
+			t.Errorf("birthday %d wasn't deleted", john.ID)
+		}
+		if current[birthday.ID].Name != birthday.Name {
+			t.Errorf("current birthday %d = %+v, server has %+v", birthday.ID, current[birthday.ID], birthday)
+		}
+	}
+
+	// Every change is in the journal
+	journal, err := helper.LoadJournal(helper.JournalFilePath(url))
+	if err != nil {
+		t.Fatalf("LoadJournal() error = %v", err)
+	}
+	if len(journal) != len(queue) {
+		t.Errorf("journal has %d entries, want %d", len(journal), len(queue))
+	}
+
+	// Corrupt changes are refused instead of panicking
+	for _, op := range []helper.QueuedOperation{{Operation: helper.JournalAdd}, {Operation: helper.JournalModify}, {Operation: helper.JournalDelete}, {Operation: "rename"}} {
+		if err := replayQueuedOperation(io.Discard, url, token, op, current, replayed); err == nil {
+			t.Errorf("replayQueuedOperation() of %+v returned no error", op)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package general
+
+import (
+	"fmt"
+	"hbd-cli/helper"
+	"hbd-cli/tui"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+func Tui() *cobra.Command {
+	var host, port, credsPath, leapDayPolicy string
+	var days int
+	var ssl bool
+
+	var tuiCmd = &cobra.Command{
+		Use:   "tui",
+		Short: "Browse and edit your birthdays in a full-screen dashboard",
+		Long: `The tui command opens a full-screen dashboard with a table of your birthdays, soonest first,
+where the ones coming up in the next --days days are highlighted. The reminder time and timezone
+of your account are shown next to it.
+
+Birthdays can be searched by name or date, added and edited in a form validated like
+'birthdays add', and deleted after a confirmation. The table is refreshed after every change,
+and changes are recorded in the journal like the birthdays commands, so they can be undone.
+
+Keys:
+  up/down - Move through the birthdays, also j/k, and faster with pgup/pgdown and home/end.
+  / - Search by name or date, esc clears the search.
+  a - Add a birthday.
+  e/enter - Edit the selected birthday.
+  d - Delete the selected birthday.
+  r - Refresh the birthdays.
+  q - Quit.
+
+Environment variables:
+  HBD_CREDS_PATH - Path to the credentials file.
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service.
+  HBD_SSL - Use SSL (https) for the connection.
+  HBD_LEAP_DAY_POLICY - When to celebrate February 29 birthdays in non-leap years (feb28 or mar1). Defaults to feb28.
+
+Example usage:
+  hbd-cli tui --days=14 --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
+		`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load env vars
+			helper.LoadEnvVars()
+
+			// The dashboard needs a terminal to draw on and read keys from
+			if !helper.IsInteractive(cmd.InOrStdin()) {
+				return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error starting dashboard", "the dashboard needs an interactive terminal, use the birthdays commands in scripts"))
+			}
+
+			// Validate the flags
+			if err := helper.ValidateLeapDayPolicy(leapDayPolicy); err != nil {
+				return helper.WithExitCode(helper.ExitUsage, helper.NewError("Error validating flags", err))
+			}
+			if days < 0 {
+				return helper.WithExitCode(helper.ExitValidation, helper.NewErrorStr("Error validating flags", fmt.Sprintf("invalid number of days %d, must be 0 or more", days)))
+			}
+
+			// Load the token
+			credsPath = filepath.Join(credsPath, host)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
+
+			// Run the dashboard until the user quits
+			model := tui.New(tui.Options{
+				URL:           helper.GenUrl(host, port, ssl),
+				Token:         token,
+				Host:          host,
+				Days:          days,
+				LeapDayPolicy: leapDayPolicy,
+			})
+			program := tea.NewProgram(model, tea.WithAltScreen(), tea.WithInput(cmd.InOrStdin()), tea.WithOutput(cmd.OutOrStdout()))
+			final, err := program.Run()
+			if err != nil {
+				return helper.NewError("Error running dashboard", err)
+			}
+
+			// The birthdays couldn't be retrieved at all, e.g. with an expired token
+			if err := final.(tui.Model).Err(); err != nil {
+				return helper.NewError("Error retrieving user data", err)
+			}
+
+			return nil
+		},
+	}
+
+	// Add flags to the tui command
+	tuiCmd.Flags().IntVar(&days, "days", 30, "Highlight the birthdays in the next number of days")
+	tuiCmd.Flags().StringVar(&leapDayPolicy, "leap-day-policy", helper.DefaultLeapDayPolicy(), "When to celebrate February 29 birthdays in non-leap years (feb28 or mar1)")
+	tuiCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	tuiCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
+	tuiCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	tuiCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
+
+	// Return the tui command
+	return tuiCmd
+}
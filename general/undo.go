@@ -0,0 +1,215 @@
+package general
+
+import (
+	"bufio"
+	"fmt"
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func Undo() *cobra.Command {
+	var host, port, credsPath string
+	var ssl, autoConfirm bool
+
+	var undoCmd = &cobra.Command{
+		Use:   "undo [n]",
+		Short: "Revert the last birthday changes made with the CLI",
+		Long: `The undo command reverts the last n birthday changes (default 1) recorded in the
+local journal, newest first, by sending the inverse operations to the HBD service:
+added birthdays are deleted, deleted birthdays are added again and modified
+birthdays get their previous name and date back.
+
+Deleted birthdays get a new ID when they are added again. The reverts are recorded
+in the journal as well, see the history command.
+
+Environment variables:
+  HBD_CREDS_PATH - Path to the credentials file.
+  HBD_JOURNAL_PATH - Path to the journal directory.
+  HBD_HOST - The host for the service. Defaults to 0.0.0.0.
+  HBD_PORT - The port for the service.
+  HBD_SSL - Use SSL (https) for the connection.
+
+Example usage:
+  hbd-cli undo --host="hbd.lotiguere.com" --ssl --creds-path="~/.hbd/credentials"
+  hbd-cli undo 3 --yes
+		`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			// Load env vars
+			helper.LoadEnvVars()
+
+			// Number of changes to revert
+			n := 1
+			if len(args) == 1 {
+				var err error
+				n, err = strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					return helper.WithExitCode(helper.ExitUsage, helper.NewErrorStr("Error parsing arguments", fmt.Sprintf("invalid number of changes %q", args[0])))
+				}
+			}
+
+			// Create the URL
+			url := helper.GenUrl(host, port, ssl)
+
+			// Load the journal
+			journalPath := helper.JournalFilePath(url)
+			entries, err := helper.LoadJournal(journalPath)
+			if err != nil {
+				return helper.NewError("Error loading journal", err)
+			}
+
+			// Pick the latest changes that are not undos and haven't been undone yet
+			undone := undoneEntries(entries)
+			var pending []helper.JournalEntry
+			for i := len(entries) - 1; i >= 0 && len(pending) < n; i-- {
+				if entries[i].Undoes == 0 && !undone[entries[i].Seq] {
+					pending = append(pending, entries[i])
+				}
+			}
+			if len(pending) == 0 {
+				fmt.Fprintln(out, "Nothing to undo.")
+				return nil
+			}
+
+			// Print a preview
+			fmt.Fprintf(out, "The following %d change(s) will be reverted:\n", len(pending))
+			for _, entry := range pending {
+				fmt.Fprintf(out, "#%d %s\n", entry.Seq, describeJournalEntry(entry))
+			}
+
+			// If autoConfirm is not set, ask for confirmation
+			if !autoConfirm {
+				reader := bufio.NewReader(cmd.InOrStdin())
+				fmt.Fprint(out, "Revert these changes? Type 'yes' to proceed: ")
+				confirmation, _ := reader.ReadString('\n')
+				confirmation = strings.TrimSpace(confirmation)
+
+				if confirmation != "yes" {
+					fmt.Fprintln(out, "Undo cancelled.")
+					return nil
+				}
+			}
+
+			// Load the token
+			credsPath = filepath.Join(credsPath, host)
+			token, err := helper.LoadToken(credsPath)
+			if err != nil {
+				return err
+			}
+
+			// Revert the changes, newest first
+			remap := reAddedIDs(entries)
+			failed := 0
+			for _, entry := range pending {
+				revert, err := revertJournalEntry(url, token, entry, remap)
+				if err != nil {
+					failed++
+					fmt.Fprintf(out, "FAILED   #%d (%v)\n", entry.Seq, err)
+					continue
+				}
+
+				// Record the revert in the journal
+				revert.Undoes = entry.Seq
+				_, err = helper.AppendJournal(journalPath, *revert)
+				helper.HandleError(cmd.ErrOrStderr(), "Warning: the revert could not be recorded in the journal", err)
+
+				fmt.Fprintf(out, "REVERTED #%d %s\n", entry.Seq, describeJournalEntry(*revert))
+			}
+
+			if failed > 0 {
+				return helper.NewErrorStr("Error undoing changes", fmt.Sprintf("%d revert(s) failed", failed))
+			}
+
+			return nil
+		},
+	}
+
+	// Add flags to the undo command
+	undoCmd.Flags().StringVar(&host, "host", helper.DefaultHost(), "Host for the service")
+	undoCmd.Flags().StringVar(&port, "port", helper.DefaultPort(), "Port for the service")
+	undoCmd.Flags().BoolVar(&ssl, "ssl", helper.DefaultSSL(), "Use SSL (https) for the connection")
+	undoCmd.Flags().StringVar(&credsPath, "creds-path", helper.GetDefaultCredsPath(), "Path to the credentials file")
+	undoCmd.Flags().BoolVarP(&autoConfirm, "yes", "y", false, "Automatic yes to confirmation prompt")
+
+	// Return the undo command
+	return undoCmd
+}
+
+// reAddedIDs maps the IDs of deleted birthdays to the IDs they got when an undo added them again
+func reAddedIDs(entries []helper.JournalEntry) map[int64]int64 {
+	bySeq := map[int]helper.JournalEntry{}
+	remap := map[int64]int64{}
+	for _, entry := range entries {
+		bySeq[entry.Seq] = entry
+
+		original, ok := bySeq[entry.Undoes]
+		if ok && original.Operation == helper.JournalDelete && original.Before != nil && entry.After != nil {
+			remap[original.Before.ID] = entry.After.ID
+		}
+	}
+	return remap
+}
+
+// revertJournalEntry sends the inverse of a journal entry to the API and returns the entry describing the revert
+func revertJournalEntry(url, token string, entry helper.JournalEntry, remap map[int64]int64) (*helper.JournalEntry, error) {
+	// Follow the IDs of birthdays that were deleted and added again
+	resolve := func(id int64) int64 {
+		for seen := 0; seen < len(remap); seen++ {
+			next, ok := remap[id]
+			if !ok {
+				break
+			}
+			id = next
+		}
+		return id
+	}
+
+	switch entry.Operation {
+	case helper.JournalAdd:
+		if entry.After == nil {
+			return nil, fmt.Errorf("the added birthday is unknown")
+		}
+		birthday := *entry.After
+		birthday.ID = resolve(birthday.ID)
+
+		if _, err := api.DeleteBirthday(url, token, structs.BirthdayNameDateModify{ID: birthday.ID}); err != nil {
+			return nil, err
+		}
+		return &helper.JournalEntry{Operation: helper.JournalDelete, Before: &birthday}, nil
+
+	case helper.JournalDelete:
+		if entry.Before == nil {
+			return nil, fmt.Errorf("the deleted birthday is unknown")
+		}
+
+		added, err := api.AddBirthday(url, token, structs.BirthdayNameDateAdd{Name: entry.Before.Name, Date: entry.Before.Date})
+		if err != nil {
+			return nil, err
+		}
+		remap[entry.Before.ID] = added.ID
+		return &helper.JournalEntry{Operation: helper.JournalAdd, After: added}, nil
+
+	case helper.JournalModify:
+		if entry.Before == nil || entry.After == nil {
+			return nil, fmt.Errorf("the previous values of the birthday are unknown")
+		}
+		current := *entry.After
+		current.ID = resolve(current.ID)
+		previous := structs.BirthdayFull{ID: current.ID, Name: entry.Before.Name, Date: entry.Before.Date}
+
+		if _, err := api.ModifyBirthday(url, token, structs.BirthdayNameDateModify(previous)); err != nil {
+			return nil, err
+		}
+		return &helper.JournalEntry{Operation: helper.JournalModify, Before: &current, After: &previous}, nil
+	}
+
+	return nil, fmt.Errorf("unknown operation %q", entry.Operation)
+}
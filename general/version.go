@@ -0,0 +1,30 @@
+package general
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func Version(versionText string) *cobra.Command {
+	var versionCmd = &cobra.Command{
+		Use:   "version",
+		Short: "Print the version of the CLI",
+		Long: `The version command prints the version of the CLI, like --version without the banner.
+
+-v is the shorthand of --verbose, so scripts that used 'hbd -v' to print the version
+should use 'hbd version' or 'hbd --version' instead.
+
+Example usage:
+  hbd-cli version
+		`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Fprint(cmd.OutOrStdout(), versionText)
+			return nil
+		},
+	}
+
+	// Return the version command
+	return versionCmd
+}
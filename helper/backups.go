@@ -0,0 +1,152 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"hbd-cli/structs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format written by this CLI
+const SnapshotVersion = 1
+
+// snapshotTimeLayout is used in snapshot file names so they sort chronologically,
+// with nanoseconds so snapshots taken in the same second don't collide
+const snapshotTimeLayout = "20060102T150405.000000000Z"
+
+// Snapshot is a point in time copy of an account's settings and birthdays
+type Snapshot struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"created_at"`
+	Host      string           `json:"host"`
+	UserData  structs.UserData `json:"user_data"`
+}
+
+// GetDefaultBackupsPath returns the default directory for account snapshots
+func GetDefaultBackupsPath() string {
+	// If there env variable HBD_BACKUPS_PATH is set, use this as default
+	if path := os.Getenv("HBD_BACKUPS_PATH"); path != "" {
+		return path
+	}
+
+	return defaultPath("backups")
+}
+
+// SaveSnapshot writes a snapshot to a new timestamped file in the directory and returns its path.
+// While replaying a cassette the path is returned without writing the file.
+func SaveSnapshot(dir string, snapshot *Snapshot) (string, error) {
+	// Interpret `~` as home directory
+	dir = InterpretTildeAsHomeDir(dir)
+
+	if Replaying() {
+		snapshot.Version = SnapshotVersion
+		return filepath.Join(dir, fmt.Sprintf("hbd-%s.json", snapshot.CreatedAt.UTC().Format(snapshotTimeLayout))), nil
+	}
+
+	// Create the directory if it does not exist
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("error creating backups directory: %v", err)
+	}
+
+	snapshot.Version = SnapshotVersion
+
+	// Never overwrite an existing snapshot, a clock too coarse to tell two snapshots apart
+	// moves the later one a nanosecond forward so the names still sort chronologically
+	var path string
+	var file *os.File
+	for {
+		path = filepath.Join(dir, fmt.Sprintf("hbd-%s.json", snapshot.CreatedAt.UTC().Format(snapshotTimeLayout)))
+
+		var err error
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("error creating snapshot file: %v", err)
+		}
+		snapshot.CreatedAt = snapshot.CreatedAt.Add(time.Nanosecond)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snapshot); err != nil {
+		return "", fmt.Errorf("error writing snapshot: %v", err)
+	}
+
+	return path, nil
+}
+
+// LoadSnapshot reads a snapshot file, refusing versions newer than this CLI understands
+func LoadSnapshot(path string) (*Snapshot, error) {
+	// Interpret `~` as home directory
+	path = InterpretTildeAsHomeDir(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %v", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("error decoding snapshot %s: %v", path, err)
+	}
+
+	if snapshot.Version < 1 || snapshot.Version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d in %s", snapshot.Version, path)
+	}
+
+	return &snapshot, nil
+}
+
+// ListSnapshots returns the snapshot files in the directory, oldest first
+func ListSnapshots(dir string) ([]string, error) {
+	// Interpret `~` as home directory
+	dir = InterpretTildeAsHomeDir(dir)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), "hbd-") || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// PruneSnapshots deletes the oldest snapshots in the directory so that at most `keep` remain,
+// a keep of 0 or less keeps every snapshot, as does replaying a cassette
+func PruneSnapshots(dir string, keep int) ([]string, error) {
+	if keep <= 0 || Replaying() {
+		return nil, nil
+	}
+
+	paths, err := ListSnapshots(dir)
+	if err != nil || len(paths) <= keep {
+		return nil, err
+	}
+
+	removed := paths[:len(paths)-keep]
+	for _, path := range removed {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("error removing old snapshot %s: %v", path, err)
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,126 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveSnapshotSameTime(t *testing.T) {
+	dir := t.TempDir()
+	createdAt := time.Date(2024, time.August, 1, 12, 0, 0, 0, time.UTC)
+
+	// Snapshots taken at the same instant, as seen by a coarse clock
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path, err := SaveSnapshot(dir, &Snapshot{CreatedAt: createdAt, Host: "test"})
+		if err != nil {
+			t.Fatalf("SaveSnapshot() #%d error = %v", i+1, err)
+		}
+		paths = append(paths, path)
+	}
+
+	listed, err := ListSnapshots(dir)
+	if err != nil {
+		t.Fatalf("ListSnapshots() error = %v", err)
+	}
+	if !reflect.DeepEqual(listed, paths) {
+		t.Errorf("ListSnapshots() = %v, want the snapshots in the order they were saved %v", listed, paths)
+	}
+
+	snapshot, err := LoadSnapshot(paths[0])
+	if err != nil {
+		t.Fatalf("LoadSnapshot() error = %v", err)
+	}
+	if snapshot.Version != SnapshotVersion || !snapshot.CreatedAt.Equal(createdAt) || snapshot.Host != "test" {
+		t.Errorf("LoadSnapshot() = %+v, want the saved snapshot", snapshot)
+	}
+}
+
+func TestSaveSnapshotSubSecond(t *testing.T) {
+	dir := t.TempDir()
+	createdAt := time.Date(2024, time.August, 1, 12, 0, 0, 0, time.UTC)
+
+	first, err := SaveSnapshot(dir, &Snapshot{CreatedAt: createdAt.Add(900 * time.Millisecond)})
+	if err != nil {
+		t.Fatalf("SaveSnapshot() error = %v", err)
+	}
+	second, err := SaveSnapshot(dir, &Snapshot{CreatedAt: createdAt.Add(time.Second)})
+	if err != nil {
+		t.Fatalf("SaveSnapshot() error = %v", err)
+	}
+
+	if got := filepath.Base(first); got != "hbd-20240801T120000.900000000Z.json" {
+		t.Errorf("first snapshot name = %s", got)
+	}
+	if filepath.Base(first) >= filepath.Base(second) {
+		t.Errorf("snapshot names %s and %s don't sort chronologically", filepath.Base(first), filepath.Base(second))
+	}
+}
+
+func TestLoadSnapshotVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hbd-future.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadSnapshot(path); err == nil {
+		t.Error("LoadSnapshot() of a newer version returned no error")
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	createdAt := time.Date(2024, time.August, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		keep        int
+		wantRemoved int
+	}{
+		{"keep fewer", 2, 3},
+		{"keep all of them", 5, 0},
+		{"keep more", 10, 0},
+		{"zero keeps every snapshot", 0, 0},
+		{"negative keeps every snapshot", -1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var paths []string
+			for i := 0; i < 5; i++ {
+				path, err := SaveSnapshot(dir, &Snapshot{CreatedAt: createdAt.Add(time.Duration(i) * time.Hour)})
+				if err != nil {
+					t.Fatalf("SaveSnapshot() error = %v", err)
+				}
+				paths = append(paths, path)
+			}
+			// Other files in the directory are left alone
+			other := filepath.Join(dir, "notes.txt")
+			if err := os.WriteFile(other, nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			removed, err := PruneSnapshots(dir, tt.keep)
+			if err != nil {
+				t.Fatalf("PruneSnapshots() error = %v", err)
+			}
+			if len(removed) != tt.wantRemoved || (tt.wantRemoved > 0 && !reflect.DeepEqual(removed, paths[:tt.wantRemoved])) {
+				t.Errorf("PruneSnapshots() removed %v, want the %d oldest", removed, tt.wantRemoved)
+			}
+
+			remaining, err := ListSnapshots(dir)
+			if err != nil {
+				t.Fatalf("ListSnapshots() error = %v", err)
+			}
+			if !reflect.DeepEqual(remaining, paths[tt.wantRemoved:]) {
+				t.Errorf("remaining snapshots = %v, want %v", remaining, paths[tt.wantRemoved:])
+			}
+			if _, err := os.Stat(other); err != nil {
+				t.Errorf("other file was removed: %v", err)
+			}
+		})
+	}
+}
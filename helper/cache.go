@@ -0,0 +1,125 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"hbd-cli/api"
+	"hbd-cli/structs"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// CachedUserData is the last user data retrieved from a host
+type CachedUserData struct {
+	FetchedAt time.Time        `json:"fetched_at"`
+	UserData  structs.UserData `json:"user_data"`
+}
+
+// GetDefaultCachePath returns the default directory for the offline cache
+func GetDefaultCachePath() string {
+	// If there env variable HBD_CACHE_PATH is set, use this as default
+	if path := os.Getenv("HBD_CACHE_PATH"); path != "" {
+		return path
+	}
+
+	return defaultPath("cache")
+}
+
+func DefaultOffline() bool {
+	// get offline mode from env vars
+	LoadEnvVars()
+	offline := viper.GetBool("HBD_OFFLINE")
+	return offline
+}
+
+// cacheFilePath returns the path of the cache file for the base URL of a service
+func cacheFilePath(baseURL string) string {
+	return filepath.Join(InterpretTildeAsHomeDir(GetDefaultCachePath()), StateKey(baseURL))
+}
+
+// LoadCachedUserData loads the cached user data of the service at the base URL
+func LoadCachedUserData(baseURL string) (*CachedUserData, error) {
+	path := cacheFilePath(baseURL)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no cached data for %s, run the command once while online", baseURL)
+		}
+		return nil, err
+	}
+
+	var cached CachedUserData
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("error decoding cached data: %v", err)
+	}
+
+	return &cached, nil
+}
+
+// SaveCachedUserData saves the user data of the service at the base URL to the cache,
+// nothing is saved while replaying a cassette
+func SaveCachedUserData(baseURL string, userData *structs.UserData) error {
+	if Replaying() {
+		return nil
+	}
+
+	path := cacheFilePath(baseURL)
+
+	// Create the directory if it does not exist
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(CachedUserData{FetchedAt: time.Now().UTC(), UserData: *userData})
+	if err != nil {
+		return err
+	}
+
+	// The user data includes the Telegram bot API key, keep it private
+	return os.WriteFile(path, data, 0600)
+}
+
+// FetchUserData retrieves the user data from the server and caches it. When offline is set or the
+// server can't be reached, the cached data is returned instead along with the time it was fetched,
+// which is zero for fresh data. Failures to update the cache are only warned about on w.
+func FetchUserData(w io.Writer, url, token string, offline bool) (*structs.UserData, time.Time, error) {
+	if !offline {
+		userData, err := api.GetUserData(url, token)
+		if err == nil {
+			HandleError(w, "Warning: the user data could not be cached", SaveCachedUserData(url, userData))
+			return userData, time.Time{}, nil
+		}
+
+		// Only fall back to the cache when the server is unreachable
+		if !api.IsNetworkError(err) {
+			return nil, time.Time{}, err
+		}
+
+		cached, cacheErr := LoadCachedUserData(url)
+		if cacheErr != nil {
+			return nil, time.Time{}, err
+		}
+		return &cached.UserData, cached.FetchedAt, nil
+	}
+
+	cached, err := LoadCachedUserData(url)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return &cached.UserData, cached.FetchedAt, nil
+}
+
+// PrintStaleNotice warns that the output comes from the cache, if it does, meant for stderr
+func PrintStaleNotice(w io.Writer, fetchedAt time.Time) {
+	if fetchedAt.IsZero() {
+		return
+	}
+
+	age := time.Since(fetchedAt).Round(time.Second)
+	fmt.Fprintf(w, "Warning: showing cached data from %s (%s old), it may be stale.\n", fetchedAt.Local().Format("2006-01-02 15:04:05"), age)
+}
@@ -0,0 +1,82 @@
+package helper
+
+import (
+	"hbd-cli/structs"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestCachedUserDataByBaseURL(t *testing.T) {
+	t.Setenv("HBD_CACHE_PATH", t.TempDir())
+
+	// Instances on the same host told apart by their scheme, port and path prefix
+	urls := []string{
+		"http://hbd.example.com",
+		"https://hbd.example.com",
+		"http://hbd.example.com:8418",
+		"https://hbd.example.com/hbd",
+		"https://hbd.example.com/other",
+		"unix:///run/hbd.sock",
+	}
+	for i, url := range urls {
+		userData := &structs.UserData{ID: int64(i + 1)}
+		if err := SaveCachedUserData(url, userData); err != nil {
+			t.Fatalf("SaveCachedUserData(%s) error = %v", url, err)
+		}
+	}
+
+	for i, url := range urls {
+		cached, err := LoadCachedUserData(url)
+		if err != nil {
+			t.Fatalf("LoadCachedUserData(%s) error = %v", url, err)
+		}
+		if cached.UserData.ID != int64(i+1) {
+			t.Errorf("LoadCachedUserData(%s) = the data of user %d, want user %d", url, cached.UserData.ID, i+1)
+		}
+	}
+
+	// A trailing slash is the same instance
+	cached, err := LoadCachedUserData("https://hbd.example.com/hbd/")
+	if err != nil || cached.UserData.ID != 4 {
+		t.Errorf("LoadCachedUserData() with a trailing slash = %+v, %v, want the data of user 4", cached, err)
+	}
+
+	if _, err := LoadCachedUserData("http://hbd.example.com:8419"); err == nil {
+		t.Error("LoadCachedUserData() of an uncached instance returned no error")
+	}
+}
+
+func TestCachedUserDataBySocket(t *testing.T) {
+	t.Setenv("HBD_CACHE_PATH", t.TempDir())
+
+	// The flags are set by ApplyURL, so each URL needs new ones
+	apply := func(rawURL string) {
+		t.Helper()
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.String("host", "0.0.0.0", "")
+		if err := ApplyURL(flags, rawURL); err != nil {
+			t.Fatalf("ApplyURL(%s) error = %v", rawURL, err)
+		}
+	}
+	defer apply("")
+
+	// Every socket has the same base URL as a TCP server on localhost
+	for i, rawURL := range []string{"unix:///run/hbd.sock", "unix:///run/other.sock", "http://localhost"} {
+		apply(rawURL)
+		if err := SaveCachedUserData(GenUrl("", "", false), &structs.UserData{ID: int64(i + 1)}); err != nil {
+			t.Fatalf("SaveCachedUserData() for %s error = %v", rawURL, err)
+		}
+	}
+
+	for i, rawURL := range []string{"unix:///run/hbd.sock", "unix:///run/other.sock", "http://localhost"} {
+		apply(rawURL)
+		cached, err := LoadCachedUserData(GenUrl("", "", false))
+		if err != nil {
+			t.Fatalf("LoadCachedUserData() for %s error = %v", rawURL, err)
+		}
+		if cached.UserData.ID != int64(i+1) {
+			t.Errorf("LoadCachedUserData() for %s = the data of user %d, want user %d", rawURL, cached.UserData.ID, i+1)
+		}
+	}
+}
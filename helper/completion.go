@@ -0,0 +1,191 @@
+package helper
+
+import (
+	"fmt"
+	"hbd-cli/api"
+	"hbd-cli/structs"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds the request made to complete birthday IDs, completions must be quick
+const completionTimeout = time.Second
+
+// zoneinfoDirs are where the IANA time zone database is usually installed, after $ZONEINFO
+var zoneinfoDirs = []string{"/usr/share/zoneinfo", "/usr/share/lib/zoneinfo", "/usr/lib/locale/TZ"}
+
+// prepareCompletion applies the global flags, like --url and the TLS flags, since cobra doesn't run
+// the persistent pre-run for completions
+func prepareCompletion(cmd *cobra.Command) {
+	if root := cmd.Root(); root.PersistentPreRunE != nil {
+		root.PersistentPreRunE(cmd, nil)
+	}
+}
+
+// CompleteBirthdayIDs completes the IDs of the birthdays of the account, described by their name and date.
+// They come from the server when it answers quickly, and from the offline cache otherwise.
+// The IDs already given as arguments are left out, and comma-separated lists are completed.
+func CompleteBirthdayIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prepareCompletion(cmd)
+
+	given := map[string]bool{}
+	for _, arg := range args {
+		given[arg] = true
+	}
+
+	// Complete the last ID of a list like 1,3,
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, toComplete = toComplete[:i+1], toComplete[i+1:]
+		for _, id := range strings.Split(prefix, ",") {
+			given[id] = true
+		}
+	}
+
+	var completions []string
+	for _, birthday := range completionBirthdays(cmd) {
+		id := strconv.FormatInt(birthday.ID, 10)
+		if given[id] || !strings.HasPrefix(id, toComplete) {
+			continue
+		}
+		completions = append(completions, fmt.Sprintf("%s%s\t%s (%s)", prefix, id, birthday.Name, birthday.Date))
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionBirthdays returns the birthdays of the account for the host of the command, none if they
+// can't be retrieved since completions can't show errors
+func completionBirthdays(cmd *cobra.Command) []structs.BirthdayFull {
+	host, _ := cmd.Flags().GetString("host")
+	port, _ := cmd.Flags().GetString("port")
+	ssl, _ := cmd.Flags().GetBool("ssl")
+	credsPath, _ := cmd.Flags().GetString("creds-path")
+	url := GenUrl(host, port, ssl)
+
+	if !DefaultOffline() {
+		token, err := LoadToken(filepath.Join(credsPath, host))
+		if err == nil {
+			userData, err := api.GetUserDataWithTimeout(url, token, completionTimeout)
+			if err == nil {
+				SaveCachedUserData(url, userData)
+				return userData.Birthdays
+			}
+		}
+	}
+
+	cached, err := LoadCachedUserData(url)
+	if err != nil {
+		return nil
+	}
+	return cached.UserData.Birthdays
+}
+
+// CompleteTimezones completes the IANA time zones installed on the system
+func CompleteTimezones(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var completions []string
+	for _, zone := range timezones() {
+		if strings.HasPrefix(zone, toComplete) {
+			completions = append(completions, zone)
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// timezones returns the names of the zones of the first time zone database found, or only UTC
+func timezones() []string {
+	dirs := zoneinfoDirs
+	if dir := os.Getenv("ZONEINFO"); dir != "" {
+		dirs = append([]string{dir}, dirs...)
+	}
+
+	for _, dir := range dirs {
+		var zones []string
+		filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			// posix and right are copies of the database with other leap second rules
+			if entry.IsDir() && (entry.Name() == "posix" || entry.Name() == "right") {
+				return filepath.SkipDir
+			}
+			if entry.IsDir() || !isZoneFile(path) {
+				return nil
+			}
+
+			name, err := filepath.Rel(dir, path)
+			if err == nil && name != "localtime" && name != "posixrules" && name != "Factory" {
+				zones = append(zones, filepath.ToSlash(name))
+			}
+			return nil
+		})
+
+		if len(zones) > 0 {
+			sort.Strings(zones)
+			return zones
+		}
+	}
+
+	return []string{"UTC"}
+}
+
+// isZoneFile reports whether the file is a compiled time zone, they start with TZif
+func isZoneFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	_, err = f.Read(magic)
+	return err == nil && string(magic) == "TZif"
+}
+
+// CompleteReminderTime completes reminder times in the HH:MM format, every half hour,
+// or every 5 minutes once the hour is typed
+func CompleteReminderTime(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var times []string
+	if hour, _, ok := strings.Cut(toComplete, ":"); ok {
+		if h, err := strconv.Atoi(hour); err == nil && len(hour) == 2 && h < 24 {
+			for minute := 0; minute < 60; minute += 5 {
+				times = append(times, fmt.Sprintf("%s:%02d", hour, minute))
+			}
+		}
+	} else {
+		for hour := 0; hour < 24; hour++ {
+			times = append(times, fmt.Sprintf("%02d:00", hour), fmt.Sprintf("%02d:30", hour))
+		}
+	}
+
+	var completions []string
+	for _, t := range times {
+		if strings.HasPrefix(t, toComplete) {
+			completions = append(completions, t)
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteBirthdayNames completes the names of the birthdays of the account
+func CompleteBirthdayNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prepareCompletion(cmd)
+
+	var completions []string
+	for _, birthday := range completionBirthdays(cmd) {
+		if strings.HasPrefix(strings.ToLower(birthday.Name), strings.ToLower(toComplete)) {
+			completions = append(completions, fmt.Sprintf("%s\tID %d (%s)", birthday.Name, birthday.ID, birthday.Date))
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,80 @@
+package helper
+
+import (
+	"hbd-cli/structs"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCompleteBirthdayIDs(t *testing.T) {
+	t.Setenv("HBD_CACHE_PATH", t.TempDir())
+	t.Setenv("HBD_OFFLINE", "true")
+
+	userData := &structs.UserData{Birthdays: []structs.BirthdayFull{
+		{ID: 1, Name: "John Doe", Date: "2000-02-29"},
+		{ID: 2, Name: "Jane Doe", Date: "1990-12-01"},
+		{ID: 12, Name: "Max Power", Date: "1985-07-14"},
+	}}
+	if err := SaveCachedUserData("http://hbd.example.com", userData); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{Use: "delete"}
+	cmd.Flags().String("host", "hbd.example.com", "")
+
+	tests := []struct {
+		args       []string
+		toComplete string
+		want       []string
+	}{
+		{nil, "", []string{"1\tJohn Doe (2000-02-29)", "2\tJane Doe (1990-12-01)", "12\tMax Power (1985-07-14)"}},
+		{nil, "1", []string{"1\tJohn Doe (2000-02-29)", "12\tMax Power (1985-07-14)"}},
+		{[]string{"1"}, "", []string{"2\tJane Doe (1990-12-01)", "12\tMax Power (1985-07-14)"}},
+		{nil, "2,1", []string{"2,1\tJohn Doe (2000-02-29)", "2,12\tMax Power (1985-07-14)"}},
+	}
+
+	for _, tt := range tests {
+		got, directive := CompleteBirthdayIDs(cmd, tt.args, tt.toComplete)
+		if !reflect.DeepEqual(got, tt.want) || directive != cobra.ShellCompDirectiveNoFileComp {
+			t.Errorf("CompleteBirthdayIDs(%v, %q) = %q, %v, want %q", tt.args, tt.toComplete, got, directive, tt.want)
+		}
+	}
+}
+
+func TestCompleteReminderTime(t *testing.T) {
+	tests := []struct {
+		toComplete string
+		want       []string
+	}{
+		{"23", []string{"23:00", "23:30"}},
+		{"07:4", []string{"07:40", "07:45"}},
+		{"24:", nil},
+	}
+
+	for _, tt := range tests {
+		got, _ := CompleteReminderTime(nil, nil, tt.toComplete)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CompleteReminderTime(%q) = %q, want %q", tt.toComplete, got, tt.want)
+		}
+	}
+
+	if got, _ := CompleteReminderTime(nil, nil, ""); len(got) != 48 {
+		t.Errorf("CompleteReminderTime(\"\") returned %d times, want 48", len(got))
+	}
+}
+
+func TestCompleteTimezones(t *testing.T) {
+	t.Setenv("ZONEINFO", t.TempDir())
+
+	got, _ := CompleteTimezones(nil, nil, "")
+	if len(got) == 1 && got[0] == "UTC" {
+		t.Skip("no time zone database installed")
+	}
+	for _, zone := range []string{"UTC", "Europe/Madrid", "America/New_York"} {
+		if got, _ := CompleteTimezones(nil, nil, zone); len(got) == 0 || got[0] != zone {
+			t.Errorf("CompleteTimezones(%q) = %q, want %s first", zone, got, zone)
+		}
+	}
+}
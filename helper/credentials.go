@@ -2,11 +2,17 @@ package helper
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/spf13/viper"
 )
 
+// ErrNotLoggedIn is returned when there's no token in the credentials file nor in HBD_TOKEN
+var ErrNotLoggedIn = errors.New("not logged in, run `hbd auth login` or set HBD_TOKEN")
+
 type Credentials struct {
 	Token string `json:"token"`
 }
@@ -18,11 +24,7 @@ func GetDefaultCredsPath() string {
 		return path
 	}
 
-	// Get the home directory
-	home, err := os.UserHomeDir()
-	HandleErrorExit("Error finding home directory", err)
-
-	return filepath.Join(home, ".hbd", "credentials")
+	return defaultPath("credentials")
 }
 
 // LoadCredentials loads the credentials from the specified path
@@ -33,9 +35,9 @@ func LoadCredentials(path string) (*Credentials, error) {
 	// Open the file
 	file, err := os.Open(path)
 	if err != nil {
-		// If the file does not exist, return an error
+		// If the file does not exist, the user is not logged in
 		if os.IsNotExist(err) {
-			return &Credentials{}, fmt.Errorf("Credentials file does not exist at %s", path)
+			return nil, fmt.Errorf("Credentials file does not exist at %s: %w", path, ErrNotLoggedIn)
 		}
 		return nil, err
 	}
@@ -50,26 +52,67 @@ func LoadCredentials(path string) (*Credentials, error) {
 	return &creds, nil
 }
 
-// SaveCredentials saves the credentials to the specified path
+// LoadToken returns the token from the credentials file, or from the HBD_TOKEN environment variable
+// when the file doesn't exist or has no token
+func LoadToken(path string) (string, error) {
+	if token, ok := sessionToken(path); ok {
+		return token, nil
+	}
+
+	creds, err := LoadCredentials(path)
+	if err != nil && !errors.Is(err, ErrNotLoggedIn) {
+		return "", NewError("Error loading credentials from credentials file", err)
+	}
+	if creds != nil && creds.Token != "" {
+		setSessionToken(path, creds.Token)
+		return creds.Token, nil
+	}
+
+	if token := viper.GetString("HBD_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	if err == nil {
+		err = ErrNotLoggedIn
+	}
+	return "", NewError("Error loading credentials from credentials file", err)
+}
+
+// SaveCredentials saves the credentials to the specified path, nothing is saved while replaying a cassette
 func SaveCredentials(path string, creds *Credentials) error {
+	if Replaying() {
+		return nil
+	}
+
 	// Interpret `~` as home directory
 	path = InterpretTildeAsHomeDir(path)
 
 	// Create the directory if it does not exist
-	err := os.MkdirAll(filepath.Dir(path), 0700)
-	HandleErrorExit("Error creating credentials directory", err)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("error creating credentials directory: %v", err)
+	}
 
 	// Open the file
 	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	HandleErrorExit("Error opening credentials file", err)
+	if err != nil {
+		return fmt.Errorf("error opening credentials file: %v", err)
+	}
 	defer file.Close()
 
 	// Encode the credentials
-	return json.NewEncoder(file).Encode(creds)
+	if err := json.NewEncoder(file).Encode(creds); err != nil {
+		return err
+	}
+	setSessionToken(path, creds.Token)
+	return nil
 }
 
-// DeleteCredentials deletes the credentials file at the specified path.
+// DeleteCredentials deletes the credentials file at the specified path, it's kept while replaying a cassette.
 func DeleteCredentials(credsPath string) error {
+	if Replaying() {
+		return nil
+	}
+
 	// Interpret `~` as home directory
 	credsPath = InterpretTildeAsHomeDir(credsPath)
 
@@ -78,5 +121,6 @@ func DeleteCredentials(credsPath string) error {
 	if err != nil {
 		return fmt.Errorf("Failed to delete credentials file at %s: %v", credsPath, err)
 	}
+	setSessionToken(credsPath, "")
 	return nil
 }
@@ -0,0 +1,104 @@
+package helper
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Leap day policies decide when people born on February 29 celebrate in non-leap years
+const (
+	LeapDayFeb28 = "feb28"
+	LeapDayMar1  = "mar1"
+)
+
+// DateLayout is the format the HBD backend uses for birthday dates
+const DateLayout = "2006-01-02"
+
+// DefaultLeapDayPolicy returns the leap day policy from env vars, defaulting to feb28
+func DefaultLeapDayPolicy() string {
+	// get policy from env vars
+	LoadEnvVars()
+	policy := viper.GetString("HBD_LEAP_DAY_POLICY")
+
+	// if the policy is empty, use feb28
+	if policy == "" {
+		policy = LeapDayFeb28
+	}
+
+	return policy
+}
+
+// ValidateLeapDayPolicy returns an error if the policy is not a known one
+func ValidateLeapDayPolicy(policy string) error {
+	switch policy {
+	case LeapDayFeb28, LeapDayMar1:
+		return nil
+	}
+	return fmt.Errorf("invalid leap day policy %q, must be %q or %q", policy, LeapDayFeb28, LeapDayMar1)
+}
+
+// ParseBirthdayDate parses a birthday date as returned by the backend (YYYY-MM-DD)
+func ParseBirthdayDate(date string) (time.Time, error) {
+	// Some backends return a full timestamp, only the date part matters
+	if len(date) > len(DateLayout) {
+		date = date[:len(DateLayout)]
+	}
+
+	return time.Parse(DateLayout, date)
+}
+
+// isLeapYear reports whether the year has a February 29
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// OccurrenceInYear returns the date on which a birthday is celebrated in the given year,
+// applying the leap day policy for people born on February 29
+func OccurrenceInYear(birth time.Time, year int, policy string, loc *time.Location) time.Time {
+	month, day := birth.Month(), birth.Day()
+
+	if month == time.February && day == 29 && !isLeapYear(year) {
+		if policy == LeapDayMar1 {
+			month, day = time.March, 1
+		} else {
+			day = 28
+		}
+	}
+
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// NextOccurrence returns the next date (today included) on which the birthday is celebrated
+func NextOccurrence(birth, now time.Time, policy string) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	next := OccurrenceInYear(birth, today.Year(), policy, today.Location())
+	if next.Before(today) {
+		next = OccurrenceInYear(birth, today.Year()+1, policy, today.Location())
+	}
+
+	return next
+}
+
+// DaysUntil returns the number of days from now until the next occurrence of the birthday
+func DaysUntil(birth, now time.Time, policy string) int {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	next := NextOccurrence(birth, now, policy)
+	next = time.Date(next.Year(), next.Month(), next.Day(), 0, 0, 0, 0, time.UTC)
+
+	return int(next.Sub(today).Hours() / 24)
+}
+
+// Age returns how many years old the person is on the date of now
+func Age(birth, now time.Time, policy string) int {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	age := today.Year() - birth.Year()
+	if today.Before(OccurrenceInYear(birth, today.Year(), policy, today.Location())) {
+		age--
+	}
+
+	return age
+}
@@ -0,0 +1,125 @@
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+func mustDate(t *testing.T, date string) time.Time {
+	t.Helper()
+	d, err := ParseBirthdayDate(date)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", date, err)
+	}
+	return d
+}
+
+func TestOccurrenceInYear(t *testing.T) {
+	tests := []struct {
+		name   string
+		birth  string
+		year   int
+		policy string
+		want   string
+	}{
+		{"leap day in leap year feb28", "2000-02-29", 2024, LeapDayFeb28, "2024-02-29"},
+		{"leap day in leap year mar1", "2000-02-29", 2024, LeapDayMar1, "2024-02-29"},
+		{"leap day in non-leap year feb28", "2000-02-29", 2025, LeapDayFeb28, "2025-02-28"},
+		{"leap day in non-leap year mar1", "2000-02-29", 2025, LeapDayMar1, "2025-03-01"},
+		{"leap day in century non-leap year", "2000-02-29", 2100, LeapDayMar1, "2100-03-01"},
+		{"leap day in 400 year leap year", "1996-02-29", 2400, LeapDayMar1, "2400-02-29"},
+		{"regular day unaffected by policy", "1990-02-28", 2025, LeapDayMar1, "2025-02-28"},
+		{"march first unaffected by policy", "1990-03-01", 2025, LeapDayFeb28, "2025-03-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := OccurrenceInYear(mustDate(t, tt.birth), tt.year, tt.policy, time.UTC)
+			if got.Format(DateLayout) != tt.want {
+				t.Errorf("OccurrenceInYear() = %s, want %s", got.Format(DateLayout), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextOccurrenceAndDaysUntil(t *testing.T) {
+	tests := []struct {
+		name     string
+		birth    string
+		now      string
+		policy   string
+		wantNext string
+		wantDays int
+	}{
+		{"today counts as next", "1990-06-15", "2025-06-15", LeapDayFeb28, "2025-06-15", 0},
+		{"later this year", "1990-06-15", "2025-06-01", LeapDayFeb28, "2025-06-15", 14},
+		{"already passed rolls over", "1990-01-10", "2025-12-31", LeapDayFeb28, "2026-01-10", 10},
+		{"leap day feb28 in non-leap year", "2000-02-29", "2025-02-01", LeapDayFeb28, "2025-02-28", 27},
+		{"leap day mar1 in non-leap year", "2000-02-29", "2025-02-01", LeapDayMar1, "2025-03-01", 28},
+		{"leap day on feb28 with mar1 policy", "2000-02-29", "2025-02-28", LeapDayMar1, "2025-03-01", 1},
+		{"leap day passed rolls to leap year", "2000-02-29", "2027-03-02", LeapDayFeb28, "2028-02-29", 364},
+		{"leap day in leap year", "2000-02-29", "2024-02-01", LeapDayMar1, "2024-02-29", 28},
+		{"leap day mar1 is today", "2000-02-29", "2025-03-01", LeapDayMar1, "2025-03-01", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			birth, now := mustDate(t, tt.birth), mustDate(t, tt.now)
+
+			next := NextOccurrence(birth, now, tt.policy)
+			if next.Format(DateLayout) != tt.wantNext {
+				t.Errorf("NextOccurrence() = %s, want %s", next.Format(DateLayout), tt.wantNext)
+			}
+
+			if days := DaysUntil(birth, now, tt.policy); days != tt.wantDays {
+				t.Errorf("DaysUntil() = %d, want %d", days, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestAge(t *testing.T) {
+	tests := []struct {
+		name   string
+		birth  string
+		now    string
+		policy string
+		want   int
+	}{
+		{"day before birthday", "1990-06-15", "2025-06-14", LeapDayFeb28, 34},
+		{"on birthday", "1990-06-15", "2025-06-15", LeapDayFeb28, 35},
+		{"leap day on feb28 with feb28 policy", "2000-02-29", "2025-02-28", LeapDayFeb28, 25},
+		{"leap day on feb28 with mar1 policy", "2000-02-29", "2025-02-28", LeapDayMar1, 24},
+		{"leap day on mar1 with mar1 policy", "2000-02-29", "2025-03-01", LeapDayMar1, 25},
+		{"leap day on feb28 of leap year", "2000-02-29", "2024-02-28", LeapDayFeb28, 23},
+		{"leap day on feb29 of leap year", "2000-02-29", "2024-02-29", LeapDayMar1, 24},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Age(mustDate(t, tt.birth), mustDate(t, tt.now), tt.policy); got != tt.want {
+				t.Errorf("Age() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLeapDayPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		wantErr bool
+	}{
+		{LeapDayFeb28, false},
+		{LeapDayMar1, false},
+		{"", true},
+		{"feb29", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			if err := ValidateLeapDayPolicy(tt.policy); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateLeapDayPolicy(%q) error = %v, wantErr %v", tt.policy, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,58 +1,191 @@
 package helper
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"hbd-cli/api"
+	"io"
+	"io/fs"
+	"net/http"
 )
 
-// HandleErrorExit prints an error message and exits the program if an error is not nil
-func HandleErrorExit(msg string, err error) {
-	if err != nil {
-		errorMsgWithError(msg, err)
-		os.Exit(1)
+// Exit codes of hbd, documented in the README
+const (
+	ExitOK         = 0
+	ExitError      = 1 // any other failure
+	ExitUsage      = 2 // unknown command, missing or conflicting flags, values that can't be parsed or aren't one of the choices
+	ExitAuth       = 3 // not logged in, or the credentials were rejected by the server
+	ExitNotFound   = 4 // the birthday, change or file doesn't exist
+	ExitNetwork    = 5 // the server could not be reached
+	ExitServer     = 6 // the server failed to handle the request
+	ExitValidation = 7 // a well-formed value was rejected, by the CLI or the server
+)
+
+// errorKinds names the exit codes in JSON error reports
+var errorKinds = map[int]string{
+	ExitError:      "error",
+	ExitUsage:      "usage",
+	ExitAuth:       "auth",
+	ExitNotFound:   "not_found",
+	ExitNetwork:    "network",
+	ExitServer:     "server",
+	ExitValidation: "validation",
+}
+
+// errorHints suggest what to do about each kind of error
+var errorHints = map[int]string{
+	ExitUsage:      "run the command with --help to see its flags and arguments",
+	ExitAuth:       "log in again with `hbd auth login` or set HBD_TOKEN",
+	ExitNetwork:    "check --host, --port and --ssl, or use --offline to read the cached data",
+	ExitServer:     "the server failed to handle the request, try again later",
+	ExitValidation: "check the values given to the command",
+}
+
+// CommandError is an error returned by a command, printed as the message followed by the cause
+type CommandError struct {
+	Msg string
+	Err error
+	// Code is the exit code, derived from the cause when zero
+	Code int
+	// Silent errors are not printed, the command already reported the failure
+	Silent bool
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Msg, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// NewError returns a command error with a message if an error is not nil
+func NewError(msg string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if msg == "" {
+		msg = "Error"
 	}
+
+	return &CommandError{Msg: msg, Err: err}
 }
 
-// HandleErrorExitStr prints an error message and exits the program if a string is not empty
-func HandleErrorExitStr(msg string, str string) {
-	if str != "" {
-		errorMsgWithStr(msg, str)
-		os.Exit(1)
+// NewErrorStr returns a command error with a message if a string is not empty
+func NewErrorStr(msg string, str string) error {
+	if str == "" {
+		return nil
 	}
+
+	return NewError(msg, errors.New(str))
 }
 
-// HandleError prints an error message if an error is not nil
-func HandleError(msg string, err error) {
-	if err != nil {
-		errorMsgWithError(msg, err)
+// NewSilentError returns a command error that only sets the exit code, for commands that report
+// failures in their own output, like monitoring plugins
+func NewSilentError(code int, err error) error {
+	return &CommandError{Msg: "Error", Err: err, Code: code, Silent: true}
+}
+
+// WithExitCode sets the exit code of a command error, instead of deriving it from its cause
+func WithExitCode(code int, err error) error {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		cmdErr.Code = code
 	}
+
+	return err
 }
 
-// HandleErrorStr prints an error message if a string is not empty
-func HandleErrorStr(msg string, str string) {
-	if str != "" {
-		errorMsgWithStr(msg, str)
+// ExitCode returns the exit code for an error returned by a command
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		if cmdErr.Code != 0 {
+			return cmdErr.Code
+		}
+		err = cmdErr.Err
+	}
+
+	var statusErr *api.StatusError
+	switch {
+	case errors.As(err, &cmdErr):
+		return ExitCode(err)
+	case errors.As(err, &statusErr):
+		return statusExitCode(statusErr.StatusCode)
+	case api.IsNetworkError(err), errors.Is(err, api.ErrNotRecorded):
+		return ExitNetwork
+	case errors.Is(err, ErrNotLoggedIn):
+		return ExitAuth
+	case errors.Is(err, fs.ErrNotExist):
+		return ExitNotFound
 	}
+
+	return ExitError
 }
 
-// errorMsgWithError prints an error message with an error
-func errorMsgWithError(msg string, err error) {
-	if msg == "" {
-		msg = "Error"
+// ErrorReport is an error as printed with --output json, for scripts to branch on its code
+type ErrorReport struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+	Endpoint   string `json:"endpoint,omitempty"`
+	Hint       string `json:"hint,omitempty"`
+}
+
+// NewErrorReport describes an error with the kind of its exit code and the request that failed, if any
+func NewErrorReport(err error, exitCode int) ErrorReport {
+	report := ErrorReport{
+		Code:    errorKinds[exitCode],
+		Message: err.Error(),
+		Hint:    errorHints[exitCode],
+	}
+	if report.Code == "" {
+		report.Code = errorKinds[ExitError]
 	}
 
-	if err != nil {
-		fmt.Printf("%s: %v\n", msg, err)
+	var statusErr *api.StatusError
+	var networkErr *api.NetworkError
+	switch {
+	case errors.As(err, &statusErr):
+		report.HTTPStatus = statusErr.StatusCode
+		report.Endpoint = statusErr.Endpoint
+	case errors.As(err, &networkErr):
+		report.Endpoint = networkErr.Endpoint
 	}
+
+	return report
 }
 
-// errorMsgWithStr prints an error message with a string
-func errorMsgWithStr(msg string, str string) {
-	if msg == "" {
-		msg = "Error"
+// PrintErrorJSON writes the report of an error as a single line of JSON
+func PrintErrorJSON(w io.Writer, report ErrorReport) error {
+	return json.NewEncoder(w).Encode(report)
+}
+
+// statusExitCode maps an HTTP error status to an exit code
+func statusExitCode(status int) int {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ExitAuth
+	case status == http.StatusNotFound:
+		return ExitNotFound
+	case status >= 500:
+		return ExitServer
+	case status >= 400:
+		return ExitValidation
 	}
 
-	if str != "" {
-		fmt.Printf("\n%s: %s\n", msg, str)
+	return ExitError
+}
+
+// HandleError prints an error message if an error is not nil, for failures that don't stop the command
+func HandleError(w io.Writer, msg string, err error) {
+	if err != nil {
+		fmt.Fprintln(w, NewError(msg, err))
 	}
 }
@@ -0,0 +1,92 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"hbd-cli/api"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"no error", nil, ExitOK},
+		{"plain error", NewErrorStr("Error", "boom"), ExitError},
+		{"explicit code", WithExitCode(ExitUsage, NewErrorStr("Error", "missing flag")), ExitUsage},
+		{"unauthorized", NewError("Error", &api.StatusError{StatusCode: http.StatusUnauthorized}), ExitAuth},
+		{"forbidden", NewError("Error", &api.StatusError{StatusCode: http.StatusForbidden}), ExitAuth},
+		{"not found", NewError("Error", &api.StatusError{StatusCode: http.StatusNotFound}), ExitNotFound},
+		{"bad request", NewError("Error", &api.StatusError{StatusCode: http.StatusBadRequest}), ExitValidation},
+		{"conflict", NewError("Error", &api.StatusError{StatusCode: http.StatusConflict}), ExitValidation},
+		{"server error", NewError("Error", &api.StatusError{StatusCode: http.StatusBadGateway}), ExitServer},
+		{"network error", NewError("Error", &api.NetworkError{Err: errors.New("connection refused")}), ExitNetwork},
+		{"not recorded", NewError("Error", api.ErrNotRecorded), ExitNetwork},
+		{"not logged in", NewError("Error", fmt.Errorf("no credentials: %w", ErrNotLoggedIn)), ExitAuth},
+		{"missing file", NewError("Error", os.ErrNotExist), ExitNotFound},
+		{"nested command error", NewError("Outer", WithExitCode(ExitValidation, NewErrorStr("Inner", "bad value"))), ExitValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTokenMissingCredentials(t *testing.T) {
+	t.Setenv("HBD_TOKEN", "")
+
+	token, err := LoadToken(filepath.Join(t.TempDir(), "missing"))
+	if token != "" || !errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("LoadToken() = %q, %v, want ErrNotLoggedIn", token, err)
+	}
+
+	t.Setenv("HBD_TOKEN", "env-token")
+	viper.AutomaticEnv()
+	if token, err := LoadToken(filepath.Join(t.TempDir(), "missing")); token != "env-token" || err != nil {
+		t.Errorf("LoadToken() = %q, %v, want the HBD_TOKEN token", token, err)
+	}
+}
+
+func TestLoadTokenCorruptCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := LoadToken(path)
+	if token != "" || err == nil || errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("LoadToken() = %q, %v, want a decoding error", token, err)
+	}
+}
+
+func TestNewErrorReport(t *testing.T) {
+	err := NewError("Error retrieving user data", &api.StatusError{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized", Message: "token expired", Endpoint: "/api/me"})
+
+	got := NewErrorReport(err, ExitCode(err))
+	want := ErrorReport{
+		Code:       "auth",
+		Message:    "Error retrieving user data: error: token expired",
+		HTTPStatus: http.StatusUnauthorized,
+		Endpoint:   "/api/me",
+		Hint:       errorHints[ExitAuth],
+	}
+	if got != want {
+		t.Errorf("NewErrorReport() = %+v, want %+v", got, want)
+	}
+
+	networkErr := NewError("Error checking health", &api.NetworkError{Err: errors.New("connection refused"), Endpoint: "/api/health"})
+	if got := NewErrorReport(networkErr, ExitCode(networkErr)); got.Code != "network" || got.Endpoint != "/api/health" || got.HTTPStatus != 0 {
+		t.Errorf("NewErrorReport() = %+v, want a network error for /api/health", got)
+	}
+}
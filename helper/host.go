@@ -1,9 +1,21 @@
 package helper
 
 import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+// baseURL is the full URL of the service from --url or HBD_URL, used instead of the host, port and ssl
+var baseURL string
+
+// socketPath is the Unix socket of the service from a unix:// --url or HBD_URL
+var socketPath string
+
 func DefaultHost() string {
 	// get host from env vars
 	LoadEnvVars()
@@ -31,7 +43,94 @@ func DefaultSSL() bool {
 	return ssl
 }
 
+func DefaultURL() string {
+	// get the full URL from env vars
+	LoadEnvVars()
+	return viper.GetString("HBD_URL")
+}
+
+// ApplyURL parses the full URL of the service, which can have a path prefix, and sets the host, port
+// and ssl flags of the command from it, so the credentials and caches are still kept per host.
+// A unix:///path/to/socket URL connects through a Unix socket, keyed by its path instead.
+// HBD_URL is ignored when any of those flags is set, but --url can't be used with them.
+func ApplyURL(flags *pflag.FlagSet, rawURL string) error {
+	baseURL = ""
+	socketPath = ""
+	if rawURL == "" {
+		return nil
+	}
+
+	for _, name := range []string{"host", "port", "ssl"} {
+		if flags.Changed(name) {
+			if flags.Changed("url") {
+				return WithExitCode(ExitUsage, NewErrorStr("Error validating flags", "--url can't be used with --host, --port or --ssl"))
+			}
+			return nil
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return WithExitCode(ExitUsage, NewError("Error validating flags", fmt.Errorf("invalid URL %q: %v", rawURL, err)))
+	}
+
+	values := map[string]string{"host": u.Hostname(), "port": u.Port(), "ssl": fmt.Sprint(u.Scheme == "https")}
+	switch {
+	case u.Scheme == "unix" && u.Host == "" && u.Path != "":
+		// The requests need a host, the dialer ignores it
+		values["host"] = UnixSocketHost(u.Path)
+		socketPath = u.Path
+		u = &url.URL{Scheme: "http", Host: "localhost"}
+	case (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.RawQuery != "" || u.Fragment != "":
+		return WithExitCode(ExitUsage, NewErrorStr("Error validating flags", fmt.Sprintf("invalid URL %q, must be http(s)://host[:port][/prefix] or unix:///path/to/socket", rawURL)))
+	}
+
+	// Only the flags of the command are set, some of them don't connect to the service
+	for name, value := range values {
+		if flags.Lookup(name) != nil {
+			if err := flags.Set(name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	baseURL = strings.TrimRight(u.String(), "/")
+	return nil
+}
+
+// UnixSocketHost returns the name the credentials and caches of a service on a Unix socket are kept under,
+// e.g. unix_run_hbd.sock for /run/hbd.sock
+func UnixSocketHost(path string) string {
+	return "unix" + strings.ReplaceAll(filepath.ToSlash(filepath.Clean(path)), "/", "_")
+}
+
+// StateKey returns the name the cache, journal and queue of the service at baseURL are kept under.
+// It's the escaped base URL, which tells apart the services on the same host by their scheme, port
+// and path prefix, or the name of the Unix socket in use, since every socket has the same base URL.
+func StateKey(baseURL string) string {
+	if socketPath != "" {
+		return UnixSocketHost(socketPath)
+	}
+
+	return url.QueryEscape(strings.TrimSuffix(baseURL, "/"))
+}
+
+// SocketPath returns the path of the Unix socket from --url or HBD_URL, empty if it isn't used
+func SocketPath() string {
+	return socketPath
+}
+
+// BaseURL returns the full URL of the service from --url or HBD_URL, empty if it isn't used
+func BaseURL() string {
+	return baseURL
+}
+
+// GenUrl returns the URL of the service, the one from --url or HBD_URL if set
 func GenUrl(host string, port string, ssl bool) string {
+	if baseURL != "" {
+		return baseURL
+	}
+
 	protocol := "http"
 	if ssl {
 		protocol = "https"
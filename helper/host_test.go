@@ -0,0 +1,92 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestApplyURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		rawURL  string
+		wantURL string
+		host    string
+		port    string
+		ssl     bool
+		socket  string
+		code    int
+	}{
+		{name: "prefix", rawURL: "https://tools.example.com/hbd/", wantURL: "https://tools.example.com/hbd", host: "tools.example.com", ssl: true},
+		{name: "port", rawURL: "http://127.0.0.1:8418", wantURL: "http://127.0.0.1:8418", host: "127.0.0.1", port: "8418"},
+		{name: "unix socket", rawURL: "unix:///run/hbd.sock", wantURL: "http://localhost", host: "unix_run_hbd.sock", socket: "/run/hbd.sock"},
+		{name: "empty", wantURL: "", host: "0.0.0.0"},
+		{name: "host flag wins over env", args: []string{"--host", "other"}, rawURL: "https://tools.example.com/hbd", host: "other"},
+		{name: "url flag with host flag", args: []string{"--url", "https://tools.example.com", "--host", "other"}, rawURL: "https://tools.example.com", host: "other", code: ExitUsage},
+		{name: "unsupported scheme", rawURL: "ftp://tools.example.com", host: "0.0.0.0", code: ExitUsage},
+		{name: "unix socket with a host", rawURL: "unix://run/hbd.sock", host: "0.0.0.0", code: ExitUsage},
+		{name: "query", rawURL: "https://tools.example.com/?a=b", host: "0.0.0.0", code: ExitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rawURL, host, port string
+			var ssl bool
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.StringVar(&rawURL, "url", tt.rawURL, "")
+			flags.StringVar(&host, "host", "0.0.0.0", "")
+			flags.StringVar(&port, "port", "", "")
+			flags.BoolVar(&ssl, "ssl", false, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			defer ApplyURL(flags, "")
+
+			err := ApplyURL(flags, rawURL)
+			if tt.code != 0 {
+				if code := ExitCode(err); err == nil || code != tt.code {
+					t.Fatalf("ApplyURL() error = %v with code %d, want code %d", err, code, tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyURL() error = %v", err)
+			}
+			if BaseURL() != tt.wantURL || host != tt.host || port != tt.port || ssl != tt.ssl || SocketPath() != tt.socket {
+				t.Errorf("ApplyURL() = %q, host %q, port %q, ssl %v, socket %q, want %q, host %q, port %q, ssl %v, socket %q",
+					BaseURL(), host, port, ssl, SocketPath(), tt.wantURL, tt.host, tt.port, tt.ssl, tt.socket)
+			}
+		})
+	}
+}
+
+func TestStateKey(t *testing.T) {
+	tests := []struct {
+		rawURL string
+		want   string
+	}{
+		{"http://localhost", "http%3A%2F%2Flocalhost"},
+		{"https://tools.example.com/hbd/", "https%3A%2F%2Ftools.example.com%2Fhbd"},
+		{"http://127.0.0.1:8418", "http%3A%2F%2F127.0.0.1%3A8418"},
+		{"unix:///run/hbd.sock", "unix_run_hbd.sock"},
+		{"unix:///var/run/other.sock", "unix_var_run_other.sock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rawURL, func(t *testing.T) {
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.String("host", "0.0.0.0", "")
+			flags.String("port", "", "")
+			flags.Bool("ssl", false, "")
+			if err := ApplyURL(flags, tt.rawURL); err != nil {
+				t.Fatalf("ApplyURL() error = %v", err)
+			}
+			defer ApplyURL(flags, "")
+
+			if got := StateKey(GenUrl("", "", false)); got != tt.want {
+				t.Errorf("StateKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package helper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hbd-cli/structs"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Operations recorded in the journal
+const (
+	JournalAdd    = "add"
+	JournalModify = "modify"
+	JournalDelete = "delete"
+)
+
+// JournalEntry is a single birthday change made by the CLI
+type JournalEntry struct {
+	Seq       int                   `json:"seq"`
+	Time      time.Time             `json:"time"`
+	Operation string                `json:"operation"`
+	Before    *structs.BirthdayFull `json:"before,omitempty"`
+	After     *structs.BirthdayFull `json:"after,omitempty"`
+	Undoes    int                   `json:"undoes,omitempty"`
+}
+
+// GetDefaultJournalPath returns the default directory for the change journals
+func GetDefaultJournalPath() string {
+	// If there env variable HBD_JOURNAL_PATH is set, use this as default
+	if path := os.Getenv("HBD_JOURNAL_PATH"); path != "" {
+		return path
+	}
+
+	return defaultPath("journal")
+}
+
+// JournalFilePath returns the path of the journal for the base URL of a service
+func JournalFilePath(baseURL string) string {
+	return filepath.Join(GetDefaultJournalPath(), StateKey(baseURL))
+}
+
+// LoadJournal reads every entry of the journal at the specified path, oldest first
+func LoadJournal(path string) ([]JournalEntry, error) {
+	// Interpret `~` as home directory
+	path = InterpretTildeAsHomeDir(path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error decoding journal entry %d: %v", len(entries)+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// AppendJournal appends an entry to the journal at the specified path, assigning its sequence number.
+// The entry is only returned while replaying a cassette.
+func AppendJournal(path string, entry JournalEntry) (*JournalEntry, error) {
+	if Replaying() {
+		return &entry, nil
+	}
+
+	// Interpret `~` as home directory
+	path = InterpretTildeAsHomeDir(path)
+
+	// Find the next sequence number
+	entries, err := LoadJournal(path)
+	if err != nil {
+		return nil, err
+	}
+	entry.Seq = 1
+	if len(entries) > 0 {
+		entry.Seq = entries[len(entries)-1].Seq + 1
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	// Create the directory if it does not exist
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	// The journal is append-only
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// RecordBirthdayChange appends a change to the journal of the service at baseURL, only warning on w
+// on failure since the change itself has already been made
+func RecordBirthdayChange(w io.Writer, baseURL, operation string, before, after *structs.BirthdayFull) {
+	path := JournalFilePath(baseURL)
+	_, err := AppendJournal(path, JournalEntry{
+		Operation: operation,
+		Before:    before,
+		After:     after,
+	})
+	HandleError(w, "Warning: the change could not be recorded in the journal", err)
+}
@@ -0,0 +1,38 @@
+package helper
+
+import (
+	"bytes"
+	"hbd-cli/structs"
+	"testing"
+)
+
+func TestRecordBirthdayChangeByBaseURL(t *testing.T) {
+	t.Setenv("HBD_JOURNAL_PATH", t.TempDir())
+
+	// Services on the same host keep separate journals
+	urls := []string{"http://hbd.example.com", "http://hbd.example.com:8418", "https://hbd.example.com/hbd"}
+	for i, url := range urls {
+		for j := 0; j <= i; j++ {
+			var warnings bytes.Buffer
+			RecordBirthdayChange(&warnings, url, JournalAdd, nil, &structs.BirthdayFull{ID: int64(j + 1), Name: "John Doe", Date: "2000-02-29"})
+			if warnings.Len() > 0 {
+				t.Fatalf("RecordBirthdayChange() warned %q", warnings.String())
+			}
+		}
+	}
+
+	for i, url := range urls {
+		entries, err := LoadJournal(JournalFilePath(url))
+		if err != nil {
+			t.Fatalf("LoadJournal(%s) error = %v", url, err)
+		}
+		if len(entries) != i+1 {
+			t.Errorf("journal of %s has %d entries, want %d", url, len(entries), i+1)
+		}
+		for j, entry := range entries {
+			if entry.Seq != j+1 {
+				t.Errorf("entry %d of %s has sequence number %d", j, url, entry.Seq)
+			}
+		}
+	}
+}
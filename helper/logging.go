@@ -0,0 +1,20 @@
+package helper
+
+import (
+	"io"
+	"log/slog"
+)
+
+// NewLogger returns a logger writing to w at the info level when verbose and at the debug level
+// when debug, or nil when neither is set
+func NewLogger(w io.Writer, verbose, debug bool) *slog.Logger {
+	level := slog.LevelInfo
+	switch {
+	case debug:
+		level = slog.LevelDebug
+	case !verbose:
+		return nil
+	}
+
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
@@ -0,0 +1,76 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MetricsContentType is the content type of the Prometheus text exposition format
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric is a metric family with its samples, like hbd_up or hbd_birthdays_upcoming
+type Metric struct {
+	Name string
+	Help string
+	// Type is gauge or counter
+	Type    string
+	Samples []Sample
+}
+
+// Sample is a value of a metric with its labels, if any
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// WriteMetrics writes metrics in the Prometheus text exposition format, metrics without samples are left out
+func WriteMetrics(w io.Writer, metrics []Metric) error {
+	var b strings.Builder
+
+	for _, metric := range metrics {
+		if len(metric.Samples) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "# HELP %s %s\n", metric.Name, helpEscaper.Replace(metric.Help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", metric.Name, metric.Type)
+		for _, sample := range metric.Samples {
+			b.WriteString(metric.Name)
+			writeLabels(&b, sample.Labels)
+			fmt.Fprintf(&b, " %s\n", strconv.FormatFloat(sample.Value, 'g', -1, 64))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeLabels writes the labels of a sample sorted by name, so the output is stable
+func writeLabels(b *strings.Builder, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("{")
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(b, `%s="%s"`, name, labelEscaper.Replace(labels[name]))
+	}
+	b.WriteString("}")
+}
@@ -0,0 +1,36 @@
+package helper
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	metrics := []Metric{
+		{Name: "hbd_up", Help: "Whether the service is up.", Type: "gauge", Samples: []Sample{{Value: 1}}},
+		{Name: "hbd_empty", Help: "Left out without samples.", Type: "gauge"},
+		{Name: "hbd_cli_info", Help: "Multi\nline \\ help.", Type: "gauge", Samples: []Sample{
+			{Labels: map[string]string{"version": `v1 "quoted"`, "commit": "abc"}, Value: 1},
+		}},
+		{Name: "hbd_probe_duration_seconds", Help: "Probe duration.", Type: "gauge", Samples: []Sample{{Value: 0.0125}}},
+	}
+
+	want := `# HELP hbd_up Whether the service is up.
+# TYPE hbd_up gauge
+hbd_up 1
+# HELP hbd_cli_info Multi\nline \\ help.
+# TYPE hbd_cli_info gauge
+hbd_cli_info{commit="abc",version="v1 \"quoted\""} 1
+# HELP hbd_probe_duration_seconds Probe duration.
+# TYPE hbd_probe_duration_seconds gauge
+hbd_probe_duration_seconds 0.0125
+`
+
+	var b bytes.Buffer
+	if err := WriteMetrics(&b, metrics); err != nil {
+		t.Fatalf("WriteMetrics() error = %v", err)
+	}
+	if b.String() != want {
+		t.Errorf("WriteMetrics() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,35 @@
+package helper
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// Output formats, text is meant for people and json for scripts
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// DefaultOutput returns the output format from HBD_OUTPUT, text if unset
+func DefaultOutput() string {
+	// get output format from env vars
+	LoadEnvVars()
+	output := viper.GetString("HBD_OUTPUT")
+
+	if output == "" {
+		output = OutputText
+	}
+
+	return output
+}
+
+// ValidateOutput checks that the output format is one of the supported ones
+func ValidateOutput(output string) error {
+	if output != OutputText && output != OutputJSON {
+		return WithExitCode(ExitUsage, NewErrorStr("Error validating flags", fmt.Sprintf("unknown output format %q, must be %q or %q", output, OutputText, OutputJSON)))
+	}
+
+	return nil
+}
@@ -9,8 +9,11 @@ import (
 func InterpretTildeAsHomeDir(path string) string {
 	// Expand `~` to home directory if present
 	if strings.HasPrefix(path, "~") {
+		// Leave the path as is if there's no home directory, using it will fail with a clear error
 		homeDir, err := os.UserHomeDir()
-		HandleErrorExit("Error fetching the home directory", err)
+		if err != nil {
+			return path
+		}
 
 		// Replace `~` with the home directory
 		path = filepath.Join(homeDir, path[1:])
@@ -18,3 +21,14 @@ func InterpretTildeAsHomeDir(path string) string {
 
 	return path
 }
+
+// defaultPath returns the path of an element in the ~/.hbd directory
+func defaultPath(elem string) string {
+	// Keep `~` if there's no home directory, it's reported when the path is used
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join("~", ".hbd", elem)
+	}
+
+	return filepath.Join(home, ".hbd", elem)
+}
@@ -0,0 +1,98 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pinPrefix is the prefix of public key pins, as used by curl's --pinnedpubkey
+const pinPrefix = "sha256//"
+
+// PinnedKeys are the SHA-256 hashes of the public keys accepted for a host, any of them must be in
+// the certificate chain of the server
+type PinnedKeys struct {
+	Pins []string `json:"pins"`
+}
+
+// GetDefaultPinsPath returns the default directory for the pinned public keys, one file per host
+func GetDefaultPinsPath() string {
+	// If there env variable HBD_PINS_PATH is set, use this as default
+	if path := os.Getenv("HBD_PINS_PATH"); path != "" {
+		return path
+	}
+
+	return defaultPath("pins")
+}
+
+// PublicKeyPin returns the pin of the public key of a certificate, sha256// followed by the base64
+// SHA-256 hash of its subject public key info
+func PublicKeyPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// NormalizePin validates a pin and adds the sha256// prefix if it's missing
+func NormalizePin(pin string) (string, error) {
+	hash := strings.TrimPrefix(pin, pinPrefix)
+	decoded, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid pin %q, must be the base64 SHA-256 hash of a public key", pin)
+	}
+
+	return pinPrefix + hash, nil
+}
+
+// LoadPins loads the pins of a host, none if the host has no pinned keys
+func LoadPins(host string) ([]string, error) {
+	path := filepath.Join(InterpretTildeAsHomeDir(GetDefaultPinsPath()), host)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var pinned PinnedKeys
+	if err := json.Unmarshal(data, &pinned); err != nil {
+		return nil, fmt.Errorf("error decoding pins of %s: %v", host, err)
+	}
+
+	return pinned.Pins, nil
+}
+
+// SavePins replaces the pins of a host, removing the file when there are none left.
+// The pins are left untouched while replaying a cassette.
+func SavePins(host string, pins []string) error {
+	if Replaying() {
+		return nil
+	}
+
+	path := filepath.Join(InterpretTildeAsHomeDir(GetDefaultPinsPath()), host)
+
+	if len(pins) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Create the directory if it does not exist
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(PinnedKeys{Pins: pins})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
@@ -0,0 +1,79 @@
+package helper
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// IsInteractive reports whether the input is a terminal a user can answer prompts on
+func IsInteractive(in io.Reader) bool {
+	file, ok := in.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fd := file.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// Prompt asks for a value on w until it passes validation, the default is used on empty input
+func Prompt(reader *bufio.Reader, w io.Writer, label, defaultValue string, validate func(string) error) (string, error) {
+	for {
+		if defaultValue != "" {
+			fmt.Fprintf(w, "%s [%s]: ", label, defaultValue)
+		} else {
+			fmt.Fprintf(w, "%s: ", label)
+		}
+
+		// Read the answer, EOF means the user gave up
+		answer, err := reader.ReadString('\n')
+		if err != nil && answer == "" {
+			fmt.Fprintln(w)
+			return "", fmt.Errorf("no input received for %s", strings.ToLower(label))
+		}
+		answer = strings.TrimSpace(answer)
+
+		if answer == "" {
+			answer = defaultValue
+		}
+
+		// Show the validation error inline and ask again
+		if validate != nil {
+			if err := validate(answer); err != nil {
+				fmt.Fprintf(w, "  %v, please try again.\n", err)
+				continue
+			}
+		}
+
+		return answer, nil
+	}
+}
+
+// Confirm asks a yes/no question on w, an empty answer picks the default
+func Confirm(reader *bufio.Reader, w io.Writer, question string, defaultYes bool) bool {
+	options := "y/N"
+	if defaultYes {
+		options = "Y/n"
+	}
+	fmt.Fprintf(w, "%s [%s]: ", question, options)
+
+	answer, err := reader.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(w)
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return defaultYes
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
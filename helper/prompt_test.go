@@ -0,0 +1,111 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrompt(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		defaultValue string
+		want         string
+		wantErr      bool
+		// wantPrompts is how many times the question is asked
+		wantPrompts int
+	}{
+		{"answer", "John Doe\n", "", "John Doe", false, 1},
+		{"answer is trimmed", "  John Doe  \n", "", "John Doe", false, 1},
+		{"last line without newline", "John Doe", "", "John Doe", false, 1},
+		{"empty answer picks the default", "\n", "Jane Doe", "Jane Doe", false, 1},
+		{"answer replaces the default", "John Doe\n", "Jane Doe", "John Doe", false, 1},
+		{"invalid answers ask again", "\n   \nJohn Doe\n", "", "John Doe", false, 3},
+		{"EOF", "", "", "", true, 1},
+		{"EOF with a default", "", "Jane Doe", "", true, 1},
+		{"EOF after invalid answers", "\n\n", "", "", true, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := Prompt(bufio.NewReader(strings.NewReader(tt.input)), &out, "Name", tt.defaultValue, ValidateBirthdayName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Prompt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Prompt() = %q, want %q", got, tt.want)
+			}
+			if prompts := strings.Count(out.String(), "Name"); prompts != tt.wantPrompts {
+				t.Errorf("Prompt() asked %d time(s), want %d:\n%s", prompts, tt.wantPrompts, out.String())
+			}
+			if retries := strings.Count(out.String(), "please try again"); retries != tt.wantPrompts-1 {
+				t.Errorf("Prompt() showed %d validation error(s), want %d:\n%s", retries, tt.wantPrompts-1, out.String())
+			}
+		})
+	}
+}
+
+func TestPromptShowsDefault(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := Prompt(bufio.NewReader(strings.NewReader("\n")), &out, "Date (YYYY-MM-DD)", "1990-01-01", nil); err != nil {
+		t.Fatalf("Prompt() error = %v", err)
+	}
+	if want := "Date (YYYY-MM-DD) [1990-01-01]: "; out.String() != want {
+		t.Errorf("Prompt() printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		defaultYes bool
+		want       bool
+	}{
+		{"yes", "yes\n", false, true},
+		{"y", "y\n", false, true},
+		{"uppercase", " YES \n", false, true},
+		{"no", "no\n", true, false},
+		{"n", "n\n", true, false},
+		{"anything else is no", "sure\n", true, false},
+		{"empty picks yes", "\n", true, true},
+		{"empty picks no", "\n", false, false},
+		{"last line without newline", "y", false, true},
+		{"EOF is no", "", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if got := Confirm(bufio.NewReader(strings.NewReader(tt.input)), &out, "Add this birthday?", tt.defaultYes); got != tt.want {
+				t.Errorf("Confirm(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			options := "[y/N]"
+			if tt.defaultYes {
+				options = "[Y/n]"
+			}
+			if !strings.HasPrefix(out.String(), "Add this birthday? "+options) {
+				t.Errorf("Confirm() printed %q, want the question with %s", out.String(), options)
+			}
+		})
+	}
+}
+
+func TestIsInteractive(t *testing.T) {
+	if IsInteractive(strings.NewReader("")) {
+		t.Error("IsInteractive() of a reader = true, want false")
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if IsInteractive(f) {
+		t.Error("IsInteractive() of a file = true, want false")
+	}
+}
@@ -0,0 +1,109 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"hbd-cli/structs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// QueuedOperation is a birthday change made while the server was unreachable, waiting to be synced.
+// Before holds the birthday as the CLI last knew it, to detect changes made on the server since.
+type QueuedOperation struct {
+	QueuedAt  time.Time             `json:"queued_at"`
+	Operation string                `json:"operation"`
+	Before    *structs.BirthdayFull `json:"before,omitempty"`
+	After     *structs.BirthdayFull `json:"after,omitempty"`
+}
+
+// GetDefaultQueuePath returns the default directory for the offline write queues
+func GetDefaultQueuePath() string {
+	// If there env variable HBD_QUEUE_PATH is set, use this as default
+	if path := os.Getenv("HBD_QUEUE_PATH"); path != "" {
+		return path
+	}
+
+	return defaultPath("queue")
+}
+
+// QueueFilePath returns the path of the queue for the base URL of a service
+func QueueFilePath(baseURL string) string {
+	return filepath.Join(GetDefaultQueuePath(), StateKey(baseURL))
+}
+
+// LoadQueue loads the queued operations at the specified path, oldest first
+func LoadQueue(path string) ([]QueuedOperation, error) {
+	// Interpret `~` as home directory
+	path = InterpretTildeAsHomeDir(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var queue []QueuedOperation
+	if err := json.Unmarshal(data, &queue); err != nil {
+		return nil, fmt.Errorf("error decoding queue %s: %v", path, err)
+	}
+
+	return queue, nil
+}
+
+// SaveQueue replaces the queued operations at the specified path, removing the file when empty.
+// The queue is left untouched while replaying a cassette.
+func SaveQueue(path string, queue []QueuedOperation) error {
+	if Replaying() {
+		return nil
+	}
+
+	// Interpret `~` as home directory
+	path = InterpretTildeAsHomeDir(path)
+
+	if len(queue) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Create the directory if it does not exist
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(queue, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a failure never loses the queue
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// QueueBirthdayChange queues a change for the service at baseURL to be replayed by the sync command
+func QueueBirthdayChange(baseURL, operation string, before, after *structs.BirthdayFull) error {
+	path := QueueFilePath(baseURL)
+
+	queue, err := LoadQueue(path)
+	if err != nil {
+		return err
+	}
+
+	queue = append(queue, QueuedOperation{
+		QueuedAt:  time.Now().UTC(),
+		Operation: operation,
+		Before:    before,
+		After:     after,
+	})
+
+	return SaveQueue(path, queue)
+}
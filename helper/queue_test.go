@@ -0,0 +1,38 @@
+package helper
+
+import (
+	"hbd-cli/structs"
+	"testing"
+)
+
+func TestQueueBirthdayChangeByBaseURL(t *testing.T) {
+	t.Setenv("HBD_QUEUE_PATH", t.TempDir())
+
+	// Services on the same host keep separate queues
+	urls := []string{"http://hbd.example.com", "http://hbd.example.com:8418", "https://hbd.example.com/hbd"}
+	for i, url := range urls {
+		for j := 0; j <= i; j++ {
+			if err := QueueBirthdayChange(url, JournalAdd, nil, &structs.BirthdayFull{Name: "John Doe", Date: "2000-02-29"}); err != nil {
+				t.Fatalf("QueueBirthdayChange(%s) error = %v", url, err)
+			}
+		}
+	}
+
+	for i, url := range urls {
+		queue, err := LoadQueue(QueueFilePath(url))
+		if err != nil {
+			t.Fatalf("LoadQueue(%s) error = %v", url, err)
+		}
+		if len(queue) != i+1 {
+			t.Errorf("queue of %s has %d changes, want %d", url, len(queue), i+1)
+		}
+	}
+
+	// An empty queue removes the file
+	if err := SaveQueue(QueueFilePath(urls[0]), nil); err != nil {
+		t.Fatalf("SaveQueue() error = %v", err)
+	}
+	if queue, err := LoadQueue(QueueFilePath(urls[0])); err != nil || len(queue) != 0 {
+		t.Errorf("LoadQueue() after emptying = %v, %v, want nothing", queue, err)
+	}
+}
@@ -0,0 +1,69 @@
+package helper
+
+import (
+	"hbd-cli/api"
+	"os"
+	"sync"
+)
+
+// session keeps the tokens and user data in memory between the commands run in `hbd shell`,
+// instead of reading them again for every command
+var session struct {
+	mu      sync.Mutex
+	enabled bool
+	// tokens are the loaded tokens by credentials path
+	tokens map[string]string
+	cache  *api.SessionCache
+}
+
+// StartSession keeps the tokens and user data in memory for the rest of the process
+func StartSession() {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.enabled = true
+	session.tokens = map[string]string{}
+	session.cache = api.NewSessionCache()
+}
+
+// GetDefaultHistoryPath returns the default path of the history of `hbd shell`
+func GetDefaultHistoryPath() string {
+	// If there env variable HBD_HISTORY_PATH is set, use this as default
+	if path := os.Getenv("HBD_HISTORY_PATH"); path != "" {
+		return path
+	}
+
+	return defaultPath("history")
+}
+
+// sessionToken returns the token loaded before from the credentials path, if any
+func sessionToken(path string) (string, bool) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	token, ok := session.tokens[InterpretTildeAsHomeDir(path)]
+	return token, ok
+}
+
+// setSessionToken remembers the token of the credentials path, or forgets it if empty.
+// The user data may belong to someone else now, so it's forgotten too.
+func setSessionToken(path, token string) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if !session.enabled {
+		return
+	}
+
+	path = InterpretTildeAsHomeDir(path)
+	if token == "" {
+		delete(session.tokens, path)
+	} else {
+		session.tokens[path] = token
+	}
+	session.cache.Clear()
+}
+
+// sessionCache returns the user data cache of the session, nil outside of one
+func sessionCache() *api.SessionCache {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.cache
+}
@@ -0,0 +1,151 @@
+package helper
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// TLSOptions configures how the connections to the HBD service are secured when --ssl is set
+type TLSOptions struct {
+	// CACertPath is a PEM bundle of certificate authorities trusted on top of the system ones
+	CACertPath string
+	// ClientCertPath and ClientKeyPath are a PEM certificate and key for mutual TLS
+	ClientCertPath string
+	ClientKeyPath  string
+	// ServerName is the name the certificate of the server is checked against, the host if empty
+	ServerName string
+	// InsecureSkipVerify disables the verification of the certificate of the server, pins are still checked
+	InsecureSkipVerify bool
+	// Pins are public key pins accepted for any host, instead of the ones saved for the host
+	Pins []string
+}
+
+// currentTLS is the TLS configuration set by ConfigureTransport, for commands that dial the server directly
+var currentTLS = struct {
+	config *tls.Config
+	pins   []string
+}{config: &tls.Config{}}
+
+// TLSConfigForHost returns the TLS configuration to connect to host, checking its public key pins if verifyPins is set
+func TLSConfigForHost(host string, verifyPins bool) *tls.Config {
+	config := currentTLS.config.Clone()
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	if verifyPins {
+		config.VerifyConnection = pinVerifier(host, currentTLS.pins)
+	}
+
+	return config
+}
+
+// buildTLSConfig creates the TLS configuration from the options
+func buildTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CACertPath != "" {
+		pem, err := os.ReadFile(InterpretTildeAsHomeDir(opts.CACertPath))
+		if err != nil {
+			return nil, NewError("Error reading CA certificate", err)
+		}
+
+		// Trust the system authorities too, the bundle only adds to them
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, NewErrorStr("Error reading CA certificate", fmt.Sprintf("no certificates found in %s", opts.CACertPath))
+		}
+		config.RootCAs = pool
+	}
+
+	if (opts.ClientCertPath == "") != (opts.ClientKeyPath == "") {
+		return nil, WithExitCode(ExitUsage, NewErrorStr("Error validating flags", "--client-cert and --client-key must be used together"))
+	}
+	if opts.ClientCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(InterpretTildeAsHomeDir(opts.ClientCertPath), InterpretTildeAsHomeDir(opts.ClientKeyPath))
+		if err != nil {
+			return nil, NewError("Error loading client certificate", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// pinVerifier checks that a public key of the certificate chain of host matches one of its pins.
+// The pins given as flags are used when set, otherwise the ones saved for the host, if any.
+func pinVerifier(host string, flagPins []string) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		pins := flagPins
+		if len(pins) == 0 {
+			saved, err := LoadPins(host)
+			if err != nil {
+				return err
+			}
+			pins = saved
+		}
+		if len(pins) == 0 {
+			return nil
+		}
+
+		for _, cert := range state.PeerCertificates {
+			if slices.Contains(pins, PublicKeyPin(cert)) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("the public key of %s doesn't match its pins, got %s", host, PublicKeyPin(state.PeerCertificates[0]))
+	}
+}
+
+// pinningTransport sends the requests through a transport per host, so the pins of the host can be
+// checked during the TLS handshake, before anything is sent
+type pinningTransport struct {
+	base       *http.Transport
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func newPinningTransport(base *http.Transport) *pinningTransport {
+	return &pinningTransport{base: base, transports: map[string]*http.Transport{}}
+}
+
+// RoundTrip sends the request through the transport of its host
+func (t *pinningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+
+	t.mu.Lock()
+	transport, ok := t.transports[host]
+	if !ok {
+		transport = t.base.Clone()
+		transport.TLSClientConfig = TLSConfigForHost(host, true)
+		t.transports[host] = transport
+	}
+	t.mu.Unlock()
+
+	return transport.RoundTrip(req)
+}
+
+// DefaultTLSOptions returns the TLS options from the HBD_CA_CERT, HBD_CLIENT_CERT, HBD_CLIENT_KEY and HBD_TLS_SERVER_NAME env vars
+func DefaultTLSOptions() TLSOptions {
+	// get the TLS options from env vars
+	LoadEnvVars()
+	return TLSOptions{
+		CACertPath:     viper.GetString("HBD_CA_CERT"),
+		ClientCertPath: viper.GetString("HBD_CLIENT_CERT"),
+		ClientKeyPath:  viper.GetString("HBD_CLIENT_KEY"),
+		ServerName:     viper.GetString("HBD_TLS_SERVER_NAME"),
+	}
+}
@@ -0,0 +1,93 @@
+package helper
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tlsGet configures the transport with opts and sends a request to the server through it
+func tlsGet(t *testing.T, server *httptest.Server, opts TLSOptions) error {
+	t.Helper()
+	if err := ConfigureTransport(TransportOptions{TLS: opts}); err != nil {
+		t.Fatalf("ConfigureTransport() error = %v", err)
+	}
+	t.Cleanup(func() { ConfigureTransport(TransportOptions{}) })
+
+	client := &http.Client{Transport: newPinningTransport(http.DefaultTransport.(*http.Transport).Clone())}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func TestTLSOptions(t *testing.T) {
+	t.Setenv("HBD_PINS_PATH", t.TempDir())
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	cert := server.Certificate()
+	pin := PublicKeyPin(cert)
+	otherPin := pinPrefix + strings.Repeat("A", 43) + "="
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tlsGet(t, server, TLSOptions{}); err == nil {
+		t.Error("request to an untrusted server error = nil")
+	}
+	if err := tlsGet(t, server, TLSOptions{CACertPath: caPath}); err != nil {
+		t.Errorf("request with --ca-cert error = %v", err)
+	}
+	if err := tlsGet(t, server, TLSOptions{InsecureSkipVerify: true}); err != nil {
+		t.Errorf("request with --insecure-skip-verify error = %v", err)
+	}
+
+	// Pins from the flags
+	if err := tlsGet(t, server, TLSOptions{CACertPath: caPath, Pins: []string{strings.TrimPrefix(pin, pinPrefix)}}); err != nil {
+		t.Errorf("request with a matching pin error = %v", err)
+	}
+	if err := tlsGet(t, server, TLSOptions{InsecureSkipVerify: true, Pins: []string{otherPin}}); err == nil || !strings.Contains(err.Error(), pin) {
+		t.Errorf("request with a mismatching pin error = %v, want the pin of the server", err)
+	}
+
+	// Pins saved for the host, replaced by the ones from the flags
+	if err := SavePins("127.0.0.1", []string{otherPin}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tlsGet(t, server, TLSOptions{CACertPath: caPath}); err == nil {
+		t.Error("request with a mismatching saved pin error = nil")
+	}
+	if err := tlsGet(t, server, TLSOptions{CACertPath: caPath, Pins: []string{pin}}); err != nil {
+		t.Errorf("request with a matching pin flag error = %v", err)
+	}
+}
+
+func TestTLSOptionsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TLSOptions
+		code int
+	}{
+		{"client cert without key", TLSOptions{ClientCertPath: "cert.pem"}, ExitUsage},
+		{"invalid pin", TLSOptions{Pins: []string{"sha256//not-base64"}}, ExitUsage},
+		{"missing CA file", TLSOptions{CACertPath: filepath.Join(t.TempDir(), "missing.pem")}, ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ConfigureTransport(TransportOptions{TLS: tt.opts})
+			if code := ExitCode(err); err == nil || code != tt.code {
+				t.Errorf("ConfigureTransport() error = %v with code %d, want code %d", err, code, tt.code)
+			}
+		})
+	}
+}
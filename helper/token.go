@@ -0,0 +1,22 @@
+package helper
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenExpiry returns when a JWT expires, zero if it doesn't. The signature can't be checked
+// without the server secret, only the server can tell if the token is actually valid.
+func TokenExpiry(token string) (time.Time, error) {
+	claims := jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("malformed token: %v", err)
+	}
+
+	if claims.ExpiresAt == nil {
+		return time.Time{}, nil
+	}
+	return claims.ExpiresAt.Time, nil
+}
@@ -0,0 +1,25 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokenExpiry(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiry)}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := TokenExpiry(token)
+	if err != nil || !got.Equal(expiry) {
+		t.Errorf("TokenExpiry() = %v, %v, want %v", got, err, expiry)
+	}
+
+	if _, err := TokenExpiry("not-a-token"); err == nil {
+		t.Error("TokenExpiry() of a malformed token error = nil")
+	}
+}
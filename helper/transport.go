@@ -0,0 +1,135 @@
+package helper
+
+import (
+	"fmt"
+	"hbd-cli/api"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/fatih/color"
+	"github.com/spf13/viper"
+)
+
+// TransportOptions configures how the requests to the HBD service are sent
+type TransportOptions struct {
+	// RecordPath is a cassette file to record every request and response to
+	RecordPath string
+	// ReplayPath is a cassette file to answer the requests from, without any network access
+	ReplayPath string
+	// Logger logs the requests when set, with their bodies at the debug level
+	Logger *slog.Logger
+	// Proxy is the URL of an http, https or socks5 proxy for every request, instead of the one from
+	// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY env vars
+	Proxy string
+	// SocketPath is a Unix socket every request is sent through, whatever its host
+	SocketPath string
+	// TLS configures the connections when --ssl is set
+	TLS TLSOptions
+	// Warnings is where the warnings about insecure settings are printed
+	Warnings io.Writer
+}
+
+// replaying is set while the requests are answered from a cassette
+var replaying bool
+
+// Replaying reports whether the requests are answered from a cassette. The credentials, cache, journal,
+// queue, backups and pins are then only read and never written, since the responses aren't real.
+func Replaying() bool {
+	return replaying
+}
+
+// ConfigureTransport sets up the transport used for every request to the HBD service
+func ConfigureTransport(opts TransportOptions) error {
+	replaying = false
+	if opts.RecordPath != "" && opts.ReplayPath != "" {
+		return WithExitCode(ExitUsage, NewErrorStr("Error validating flags", "--record and --replay can't be used together"))
+	}
+
+	// Validate the TLS flags even when replaying, so a cassette doesn't hide mistakes
+	config, err := buildTLSConfig(opts.TLS)
+	if err != nil {
+		return err
+	}
+	pins := make([]string, 0, len(opts.TLS.Pins))
+	for _, pin := range opts.TLS.Pins {
+		normalized, err := NormalizePin(pin)
+		if err != nil {
+			return WithExitCode(ExitUsage, NewError("Error validating flags", err))
+		}
+		pins = append(pins, normalized)
+	}
+	currentTLS.config = config
+	currentTLS.pins = pins
+
+	if opts.TLS.InsecureSkipVerify && opts.Warnings != nil {
+		color.New(color.FgRed, color.Bold).Fprintln(opts.Warnings, "WARNING: --insecure-skip-verify is set, the certificate of the server is NOT verified and anyone on the network can read and change the requests, including your credentials")
+	}
+
+	// Every request goes through a transport with the TLS configuration and the pins of its host
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = http.ProxyFromEnvironment
+	if opts.Proxy != "" {
+		proxyURL, err := parseProxy(opts.Proxy)
+		if err != nil {
+			return err
+		}
+		base.Proxy = http.ProxyURL(proxyURL)
+	}
+	// Requests to a Unix socket never go through the proxy
+	if opts.SocketPath != "" {
+		base = api.NewUnixSocketTransport(opts.SocketPath, base)
+	}
+	var transport http.RoundTripper = newPinningTransport(base)
+
+	switch {
+	case opts.ReplayPath != "":
+		cassette, err := api.LoadCassette(InterpretTildeAsHomeDir(opts.ReplayPath))
+		if err != nil {
+			return NewError("Error loading cassette", err)
+		}
+		transport = api.NewReplayer(cassette)
+		replaying = true
+		if opts.Warnings != nil {
+			fmt.Fprintln(opts.Warnings, "Replaying "+opts.ReplayPath+", the credentials, cache, journal, queue, backups and pins are left untouched.")
+		}
+	case opts.RecordPath != "":
+		transport = api.NewRecorder(InterpretTildeAsHomeDir(opts.RecordPath), transport)
+	}
+
+	// Log on the outside, so replayed requests are logged too
+	if opts.Logger != nil {
+		transport = api.NewLoggingTransport(opts.Logger, transport)
+	}
+
+	// Commands run in a session share the user data they retrieve, only actual requests are logged
+	if cache := sessionCache(); cache != nil {
+		transport = cache.Transport(transport)
+	}
+
+	api.SetTransport(transport)
+	return nil
+}
+
+// DefaultProxy returns the proxy from the HBD_PROXY env var
+func DefaultProxy() string {
+	// get proxy from env vars
+	LoadEnvVars()
+	return viper.GetString("HBD_PROXY")
+}
+
+// parseProxy validates the URL of a proxy, http://, https://, socks5:// or socks5h://
+func parseProxy(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, WithExitCode(ExitUsage, NewErrorStr("Error validating flags", fmt.Sprintf("invalid proxy URL %q", rawURL)))
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	default:
+		return nil, WithExitCode(ExitUsage, NewErrorStr("Error validating flags", fmt.Sprintf("unsupported proxy scheme %q, must be http, https, socks5 or socks5h", u.Scheme)))
+	}
+}
@@ -0,0 +1,107 @@
+package helper
+
+import (
+	"bytes"
+	"hbd-cli/api"
+	"hbd-cli/structs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigureTransportProxy(t *testing.T) {
+	// The proxy answers for the service, and keeps the URL it was asked for
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ready"}`))
+	}))
+	defer proxy.Close()
+
+	if err := ConfigureTransport(TransportOptions{Proxy: proxy.URL}); err != nil {
+		t.Fatalf("ConfigureTransport() error = %v", err)
+	}
+	defer ConfigureTransport(TransportOptions{})
+
+	if _, err := api.CheckHealth("http://hbd.invalid/hbd/"); err != nil {
+		t.Fatalf("CheckHealth() error = %v", err)
+	}
+	if want := "http://hbd.invalid/hbd/api/health"; requested != want {
+		t.Errorf("proxied URL = %q, want %q", requested, want)
+	}
+
+	for _, proxyURL := range []string{"ftp://proxy.invalid", "proxy.invalid:1080"} {
+		if err := ConfigureTransport(TransportOptions{Proxy: proxyURL}); ExitCode(err) != ExitUsage {
+			t.Errorf("ConfigureTransport() with proxy %q error = %v, want a usage error", proxyURL, err)
+		}
+	}
+}
+
+func TestConfigureTransportReplayLeavesStateUntouched(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"HBD_CACHE_PATH", "HBD_JOURNAL_PATH", "HBD_QUEUE_PATH", "HBD_PINS_PATH"} {
+		t.Setenv(name, filepath.Join(dir, name))
+	}
+
+	cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+	if err := (&api.Cassette{Version: api.CassetteVersion}).Save(cassettePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var warnings bytes.Buffer
+	if err := ConfigureTransport(TransportOptions{ReplayPath: cassettePath, Warnings: &warnings}); err != nil {
+		t.Fatalf("ConfigureTransport() error = %v", err)
+	}
+	defer ConfigureTransport(TransportOptions{})
+	if !Replaying() || warnings.Len() == 0 {
+		t.Fatalf("Replaying() = %v with warnings %q, want true and a warning", Replaying(), warnings.String())
+	}
+
+	// Existing credentials are neither overwritten nor deleted
+	credsPath := filepath.Join(dir, "credentials", "localhost")
+	if err := os.MkdirAll(filepath.Dir(credsPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(credsPath, []byte(`{"token":"real"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveCredentials(credsPath, &Credentials{Token: "REDACTED"}); err != nil {
+		t.Errorf("SaveCredentials() error = %v", err)
+	}
+	if err := DeleteCredentials(credsPath); err != nil {
+		t.Errorf("DeleteCredentials() error = %v", err)
+	}
+	if token, err := LoadToken(credsPath); err != nil || token != "real" {
+		t.Errorf("LoadToken() = %q, %v, want the real token", token, err)
+	}
+
+	// Nothing else is written
+	url := "http://localhost:8417"
+	birthday := &structs.BirthdayFull{ID: 1, Name: "John Doe", Date: "1990-01-01"}
+	if err := SaveCachedUserData(url, &structs.UserData{ID: 1}); err != nil {
+		t.Errorf("SaveCachedUserData() error = %v", err)
+	}
+	if _, err := AppendJournal(JournalFilePath(url), JournalEntry{Operation: JournalAdd, After: birthday}); err != nil {
+		t.Errorf("AppendJournal() error = %v", err)
+	}
+	if err := QueueBirthdayChange(url, JournalAdd, nil, birthday); err != nil {
+		t.Errorf("QueueBirthdayChange() error = %v", err)
+	}
+	if err := SavePins("localhost", []string{"sha256/AAAA"}); err != nil {
+		t.Errorf("SavePins() error = %v", err)
+	}
+	backupsPath := filepath.Join(dir, "backups")
+	if _, err := SaveSnapshot(backupsPath, &Snapshot{CreatedAt: time.Now()}); err != nil {
+		t.Errorf("SaveSnapshot() error = %v", err)
+	}
+
+	for _, path := range []string{os.Getenv("HBD_CACHE_PATH"), os.Getenv("HBD_JOURNAL_PATH"), os.Getenv("HBD_QUEUE_PATH"), os.Getenv("HBD_PINS_PATH"), backupsPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was written while replaying", path)
+		}
+	}
+}
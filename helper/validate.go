@@ -0,0 +1,29 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ValidateBirthdayName checks that a birthday name is not blank
+func ValidateBirthdayName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	return nil
+}
+
+// ValidateBirthdayDate checks that a birthday date is a real YYYY-MM-DD date that is not in the future
+func ValidateBirthdayDate(date string) error {
+	parsed, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return fmt.Errorf("date %q must be a valid date in YYYY-MM-DD format", date)
+	}
+
+	if parsed.After(time.Now()) {
+		return fmt.Errorf("date %s is in the future", date)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateBirthdayName(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{"name", "John Doe", false},
+		{"single letter", "J", false},
+		{"surrounding spaces", "  John  ", false},
+		{"empty", "", true},
+		{"spaces only", "   ", true},
+		{"tabs and newlines", "\t\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateBirthdayName(tt.in); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateBirthdayName(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateBirthdayDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{"date", "1990-01-01", false},
+		{"leap day", "2000-02-29", false},
+		{"today", time.Now().Format(DateLayout), false},
+		{"leap day in non-leap year", "2001-02-29", true},
+		{"impossible day", "2000-02-30", true},
+		{"impossible month", "2000-13-01", true},
+		{"tomorrow", time.Now().AddDate(0, 0, 1).Format(DateLayout), true},
+		{"far future", "3000-01-01", true},
+		{"day first", "01-01-1990", true},
+		{"slashes", "1990/01/01", true},
+		{"missing zero padding", "1990-1-1", true},
+		{"timestamp", "1990-01-01T00:00:00Z", true},
+		{"empty", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateBirthdayDate(tt.in); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateBirthdayDate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"hbd-cli/auth"
 	"hbd-cli/birthdays"
 	"hbd-cli/general"
+	"hbd-cli/helper"
+	"io"
+	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -11,13 +16,60 @@ import (
 var Version = "dev"
 
 func main() {
+	os.Exit(execute(newRootCmd(CheckForNewVersion())))
+}
+
+// newRootCmd builds the whole command tree, versionNotice is shown in the help and version output
+func newRootCmd(versionNotice string) *cobra.Command {
+	transportOpts := helper.TransportOptions{TLS: helper.DefaultTLSOptions()}
+	var verbose, debug bool
+	var output string
+	var baseURL string
+
 	var rootCmd = &cobra.Command{
 		Use:     "hbd",
-		Short:   general.SplashScreen(true) + "\n" + CheckForNewVersion(),
-		Long:    general.SplashScreen(true) + "\n" + CheckForNewVersion(),
-		Version: general.SplashScreen(false) + "\n" + HBDCLIVersion() + "\n",
+		Short:   general.SplashScreen(true) + "\n" + versionNotice,
+		Long:    general.SplashScreen(true) + "\n" + versionNotice,
+		Version: general.SplashScreen(false) + "\n" + HBDCLIVersion() + versionNotice + "\n",
+
+		// Errors are printed by execute, along with the usage for invalid flags and arguments
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := helper.ValidateOutput(output); err != nil {
+				return err
+			}
+
+			// A full URL replaces the host, port and ssl flags of the command
+			if err := helper.ApplyURL(cmd.Flags(), baseURL); err != nil {
+				return err
+			}
+			transportOpts.SocketPath = helper.SocketPath()
+
+			// Log to stderr so stdout stays machine-readable
+			transportOpts.Logger = helper.NewLogger(cmd.ErrOrStderr(), verbose, debug)
+			transportOpts.Warnings = cmd.ErrOrStderr()
+
+			// Set up how the requests are sent
+			return helper.ConfigureTransport(transportOpts)
+		},
 	}
 
+	// Add global flags to the root command
+	rootCmd.PersistentFlags().StringVar(&baseURL, "url", helper.DefaultURL(), "Full URL of the service, with an optional path prefix, or unix:///path/to/socket, instead of --host, --port and --ssl")
+	rootCmd.PersistentFlags().StringVar(&transportOpts.Proxy, "proxy", helper.DefaultProxy(), "Proxy for every request (http, https or socks5 URL), instead of HTTP_PROXY, HTTPS_PROXY and NO_PROXY")
+	rootCmd.PersistentFlags().StringVar(&transportOpts.RecordPath, "record", "", "Record every request and response to this cassette file, with secrets redacted")
+	rootCmd.PersistentFlags().StringVar(&transportOpts.ReplayPath, "replay", "", "Answer the requests from this cassette file instead of the network")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log every request with its status and latency to stderr")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Log every request and response with their headers and bodies to stderr, secrets are redacted")
+	rootCmd.PersistentFlags().StringVar(&transportOpts.TLS.CACertPath, "ca-cert", transportOpts.TLS.CACertPath, "PEM file with certificate authorities to trust on top of the system ones")
+	rootCmd.PersistentFlags().StringVar(&transportOpts.TLS.ClientCertPath, "client-cert", transportOpts.TLS.ClientCertPath, "PEM client certificate for mutual TLS, used with --client-key")
+	rootCmd.PersistentFlags().StringVar(&transportOpts.TLS.ClientKeyPath, "client-key", transportOpts.TLS.ClientKeyPath, "PEM private key of the client certificate")
+	rootCmd.PersistentFlags().StringVar(&transportOpts.TLS.ServerName, "tls-server-name", transportOpts.TLS.ServerName, "Name to verify the certificate of the server against, instead of the host")
+	rootCmd.PersistentFlags().BoolVar(&transportOpts.TLS.InsecureSkipVerify, "insecure-skip-verify", false, "Don't verify the certificate of the server, this is insecure")
+	rootCmd.PersistentFlags().StringSliceVar(&transportOpts.TLS.Pins, "pin-sha256", nil, "Public key pin (sha256//<base64>) the server must match, instead of the pins saved with 'hbd pin'")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", helper.DefaultOutput(), `Output format, "text" or "json" to print errors as JSON objects on stderr`)
+
 	// Create an 'auth' parent command
 	var authCmd = &cobra.Command{
 		Use:   "auth",
@@ -27,7 +79,7 @@ func main() {
 	// Create a 'birthdays' parent command
 	var birthdaysCmd = &cobra.Command{
 		Use:   "birthdays",
-		Short: "Birthday related commands (add, list, delete, modify)",
+		Short: "Birthday related commands (add, list, delete, modify, upcoming, dedupe)",
 	}
 
 	// Add authentication subcommands under the 'auth' parent command
@@ -45,14 +97,90 @@ func main() {
 	birthdaysCmd.AddCommand(birthdays.DeleteBirthday())
 	birthdaysCmd.AddCommand(birthdays.ModifyBirthday())
 	birthdaysCmd.AddCommand(birthdays.CheckBirthdays())
+	birthdaysCmd.AddCommand(birthdays.UpcomingBirthdays())
+	birthdaysCmd.AddCommand(birthdays.DedupeBirthdays())
 
 	// Add the internal verbs to the root command
 	rootCmd.AddCommand(authCmd)
 	rootCmd.AddCommand(birthdaysCmd)
 
-	// Healthcheck command
+	// Version command, since -v is the shorthand of --verbose
+	rootCmd.AddCommand(general.Version(HBDCLIVersion() + versionNotice))
+
+	// Healthcheck and diagnostics commands
 	rootCmd.AddCommand(general.HealthCheck())
+	rootCmd.AddCommand(general.Doctor(Version, GetLatestVersion))
+	rootCmd.AddCommand(general.Exporter(Version))
+
+	// TLS commands
+	rootCmd.AddCommand(general.Pin())
+
+	// Backup and restore commands
+	rootCmd.AddCommand(general.Backup())
+	rootCmd.AddCommand(general.Restore())
+
+	// Journal commands
+	rootCmd.AddCommand(general.History())
+	rootCmd.AddCommand(general.Undo())
+
+	// Offline queue command
+	rootCmd.AddCommand(general.Sync())
+
+	// Dashboard command
+	rootCmd.AddCommand(general.Tui())
+
+	// Interactive shell, every line runs a new command tree
+	rootCmd.AddCommand(general.Shell(func(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		shellRoot := newRootCmd("")
+		shellRoot.SetArgs(args)
+		if stdin != nil {
+			shellRoot.SetIn(stdin)
+		}
+		shellRoot.SetOut(stdout)
+		shellRoot.SetErr(stderr)
+		return execute(shellRoot)
+	}))
+
+	// Development commands
+	rootCmd.AddCommand(general.DevServer())
+	rootCmd.AddCommand(general.GenDocs(Version, func() *cobra.Command { return newRootCmd("") }))
+
+	return rootCmd
+}
+
+// execute runs the command tree, prints the error if any and returns the exit code
+func execute(rootCmd *cobra.Command) int {
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return 0
+	}
+
+	// Command errors carry their own exit code, anything else comes from cobra, like unknown flags
+	code := helper.ExitUsage
+	var cmdErr *helper.CommandError
+	if errors.As(err, &cmdErr) {
+		code = helper.ExitCode(err)
+		if cmdErr.Silent {
+			return code
+		}
+	}
+
+	// Scripts get a JSON object they can branch on, even if the flags couldn't be parsed
+	if output, _ := rootCmd.PersistentFlags().GetString("output"); output == helper.OutputJSON {
+		report := helper.NewErrorReport(err, code)
+		if code == helper.ExitUsage {
+			report.Hint = fmt.Sprintf("run '%s --help' to see its flags and arguments", cmd.CommandPath())
+		}
+		helper.PrintErrorJSON(cmd.ErrOrStderr(), report)
+		return code
+	}
+
+	if cmdErr == nil {
+		fmt.Fprintln(cmd.ErrOrStderr(), "Error:", err)
+		fmt.Fprint(cmd.ErrOrStderr(), cmd.UsageString())
+		return code
+	}
 
-	// Execute the root command
-	rootCmd.Execute()
+	fmt.Fprintln(cmd.ErrOrStderr(), err)
+	return code
 }
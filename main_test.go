@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"hbd-cli/devserver"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+// goldenCase is a command run against the dev server, its output is compared to testdata/golden/<name>.golden
+type goldenCase struct {
+	name  string
+	args  []string
+	stdin string
+}
+
+// The cases run in order against the same account, each one sees the changes of the previous ones
+var goldenCases = []goldenCase{
+	{name: "health", args: []string{"health"}},
+	{name: "version", args: []string{"version"}},
+	{name: "me-logged-out", args: []string{"auth", "me"}},
+	{name: "register-missing-details", args: []string{"auth", "register", "--email", "dev@hbd.lotiguere.com"}},
+	{name: "register", args: []string{"auth", "register", "--email", "dev@hbd.lotiguere.com", "--password", "secret", "--reminder-time", "09:00", "--timezone", "Europe/Madrid", "--telegram-bot-api-key", "270485614:AAHfiqksKZ8WmR2zSjiQ7jd8Eud81ggE3e-3", "--telegram-user-id", "123456789"}},
+	{name: "login-wrong-password", args: []string{"auth", "login", "--email", "dev@hbd.lotiguere.com", "--password", "wrong"}},
+	{name: "login-wrong-password-json", args: []string{"auth", "login", "--email", "dev@hbd.lotiguere.com", "--password", "wrong", "-o", "json"}},
+	{name: "login", args: []string{"auth", "login", "--email", "dev@hbd.lotiguere.com", "--password", "secret"}},
+	{name: "me", args: []string{"auth", "me"}},
+	{name: "me-dotenv", args: []string{"auth", "me", "--dotenv"}},
+	{name: "modify-user", args: []string{"auth", "modify-user", "--new-reminder-time", "10:30", "--new-timezone", "UTC"}},
+	{name: "add-missing-flags", args: []string{"birthdays", "add", "--name", "John Doe"}},
+	{name: "add-invalid-date", args: []string{"birthdays", "add", "--name", "John Doe", "--date", "2000-02-30"}},
+	{name: "add-invalid-date-json", args: []string{"birthdays", "add", "--name", "John Doe", "--date", "2000-02-30", "--output", "json"}},
+	{name: "add", args: []string{"birthdays", "add", "--name", "John Doe", "--date", "2000-02-29"}},
+	{name: "add-second", args: []string{"birthdays", "add", "--name", "Jane Doe", "--date", "1990-12-01"}},
+	{name: "add-duplicate", args: []string{"birthdays", "add", "--name", "john  doe", "--date", "2000-02-29"}},
+	{name: "list", args: []string{"birthdays", "list"}},
+	{name: "complete-delete-ids", args: []string{"__complete", "birthdays", "delete", "1", ""}},
+	{name: "complete-reminder-time", args: []string{"__complete", "auth", "modify-user", "--new-reminder-time", "09:"}},
+	{name: "modify", args: []string{"birthdays", "modify", "--id", "2", "--name", "Jane Smith"}},
+	{name: "modify-missing-id", args: []string{"birthdays", "modify", "--name", "Nobody"}},
+	{name: "modify-unknown-id", args: []string{"birthdays", "modify", "--id", "99", "--name", "Nobody"}},
+	{name: "check", args: []string{"birthdays", "check"}},
+	{name: "dedupe-dry-run", args: []string{"birthdays", "dedupe", "--dry-run"}},
+	{name: "dedupe", args: []string{"birthdays", "dedupe", "--strategy", "keep-lowest-id"}},
+	{name: "delete-no-selection", args: []string{"birthdays", "delete"}},
+	{name: "delete-cancelled", args: []string{"birthdays", "delete", "1"}, stdin: "no\n"},
+	{name: "delete-unknown-id", args: []string{"birthdays", "delete", "1", "7", "--yes"}},
+	{name: "delete", args: []string{"birthdays", "delete", "1", "--yes"}},
+	{name: "history", args: []string{"history"}},
+	{name: "undo", args: []string{"undo", "2", "--yes"}},
+	{name: "list-after-undo", args: []string{"birthdays", "list"}},
+	{name: "modify-bulk-missing-values", args: []string{"birthdays", "modify", "4-5"}},
+	{name: "modify-bulk-unknown-id", args: []string{"birthdays", "modify", "4", "9", "--date", "1990-06-15", "--yes"}},
+	{name: "modify-bulk-cancelled", args: []string{"birthdays", "modify", "--match", "^john", "--name", "John Doe"}, stdin: "no\n"},
+	{name: "modify-bulk", args: []string{"birthdays", "modify", "--match", "^john", "--name", "John Doe", "--yes"}},
+	{name: "list-after-modify", args: []string{"birthdays", "list"}},
+	{name: "sync-empty", args: []string{"sync"}},
+	{name: "backup-list-empty", args: []string{"backup", "--list"}},
+	{name: "unknown-flag", args: []string{"birthdays", "list", "--bogus"}},
+	{name: "unknown-flag-json", args: []string{"birthdays", "list", "--output", "json", "--bogus"}},
+	{name: "unknown-output", args: []string{"birthdays", "list", "--output", "yaml"}},
+	{name: "logout-cancelled", args: []string{"auth", "logout"}, stdin: "no\n"},
+	{name: "logout", args: []string{"auth", "logout", "--yes"}},
+	{name: "logout-again", args: []string{"auth", "logout", "--yes"}},
+}
+
+// timestamps are replaced in the output since they change on every run
+var timestamps = regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`)
+
+func TestGolden(t *testing.T) {
+	server, err := devserver.New(devserver.Options{Secret: []byte("golden")})
+	if err != nil {
+		t.Fatalf("devserver.New() error = %v", err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	serverURL, _ := url.Parse(ts.URL)
+
+	// Keep every file in a temporary home and ignore the environment of whoever runs the tests
+	home := t.TempDir()
+	env := map[string]string{
+		"HOME":                         home,
+		"HBD_HOST":                     serverURL.Hostname(),
+		"HBD_PORT":                     serverURL.Port(),
+		"HBD_SSL":                      "false",
+		"HBD_OFFLINE":                  "false",
+		"HBD_OUTPUT":                   "",
+		"HBD_URL":                      "",
+		"HBD_PROXY":                    "",
+		"HBD_CREDS_PATH":               filepath.Join(home, "credentials"),
+		"HBD_CACHE_PATH":               filepath.Join(home, "cache"),
+		"HBD_JOURNAL_PATH":             filepath.Join(home, "journal"),
+		"HBD_QUEUE_PATH":               filepath.Join(home, "queue"),
+		"HBD_BACKUPS_PATH":             filepath.Join(home, "backups"),
+		"HBD_PINS_PATH":                filepath.Join(home, "pins"),
+		"HBD_HISTORY_PATH":             filepath.Join(home, "history"),
+		"HBD_CA_CERT":                  "",
+		"HBD_CLIENT_CERT":              "",
+		"HBD_CLIENT_KEY":               "",
+		"HBD_TLS_SERVER_NAME":          "",
+		"HBD_LEAP_DAY_POLICY":          "",
+		"HBD_TOKEN":                    "",
+		"HBD_EMAIL":                    "",
+		"HBD_PASSWORD":                 "",
+		"HBD_REMINDER_TIME":            "",
+		"HBD_TIMEZONE":                 "",
+		"HBD_TELEGRAM_BOT_API_KEY":     "",
+		"HBD_TELEGRAM_USER_ID":         "",
+		"HBD_NEW_EMAIL":                "",
+		"HBD_NEW_PASSWORD":             "",
+		"HBD_NEW_REMINDER_TIME":        "",
+		"HBD_NEW_TIMEZONE":             "",
+		"HBD_NEW_TELEGRAM_BOT_API_KEY": "",
+		"HBD_NEW_TELEGRAM_USER_ID":     "",
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	replacer := strings.NewReplacer(home, "$HOME", serverURL.Host, "$SERVER", serverURL.Port(), "$PORT")
+
+	for _, c := range goldenCases {
+		// Subtests share the account, stop at the first failure since the next ones depend on it
+		ok := t.Run(c.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			rootCmd := newRootCmd("")
+			rootCmd.SetArgs(c.args)
+			rootCmd.SetIn(strings.NewReader(c.stdin))
+			rootCmd.SetOut(&stdout)
+			rootCmd.SetErr(&stderr)
+			code := execute(rootCmd)
+
+			normalize := func(s string) string {
+				return timestamps.ReplaceAllString(replacer.Replace(s), "<time>")
+			}
+			got := fmt.Sprintf("$ hbd %s\n-- stdout --\n%s-- stderr --\n%s-- exit code --\n%d\n",
+				strings.Join(c.args, " "), normalize(stdout.String()), normalize(stderr.String()), code)
+
+			path := filepath.Join("testdata", "golden", c.name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("missing golden file, run the tests with -update: %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("output differs from %s, run the tests with -update if the change is intended\n--- got\n%s--- want\n%s", path, got, want)
+			}
+		})
+		if !ok {
+			break
+		}
+	}
+}
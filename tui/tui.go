@@ -0,0 +1,486 @@
+// Package tui implements the full-screen dashboard of `hbd tui`.
+//
+// It shows the birthdays of the account in a scrollable, searchable table with the upcoming
+// ones highlighted, along with the reminder settings of the account. Birthdays are added,
+// edited and deleted through package api, like the birthdays commands, and the table is
+// refreshed in place after every change.
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"hbd-cli/api"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Options configures the dashboard
+type Options struct {
+	// URL is the base URL of the service, which names the cache and journal of the account,
+	// and Token the token of the account
+	URL   string
+	Token string
+
+	// Host is shown in the title
+	Host string
+
+	// Days is how many days ahead birthdays are highlighted as upcoming
+	Days int
+
+	// LeapDayPolicy is when February 29 birthdays are celebrated in non-leap years (feb28 or mar1)
+	LeapDayPolicy string
+
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+}
+
+// mode is what the keys act on
+type mode int
+
+const (
+	modeBrowse mode = iota
+	modeSearch
+	modeForm
+	modeConfirmDelete
+)
+
+// Fields of the birthday form
+const (
+	fieldName = iota
+	fieldDate
+)
+
+// row is a birthday along with its next occurrence, Valid is false when its date can't be parsed
+type row struct {
+	structs.BirthdayFull
+	Next     time.Time
+	DaysLeft int
+	Turns    int
+	Valid    bool
+}
+
+// form adds a birthday, or edits the one in before
+type form struct {
+	before *structs.BirthdayFull
+	inputs []textinput.Model
+	focus  int
+	err    error
+}
+
+// userDataMsg carries the user data retrieved from the server
+type userDataMsg struct {
+	userData *structs.UserData
+	err      error
+}
+
+// changeMsg reports the result of adding, editing or deleting a birthday, id is the birthday to select
+type changeMsg struct {
+	id     int64
+	status string
+	err    error
+}
+
+// Model is the bubbletea model of the dashboard
+type Model struct {
+	opts Options
+
+	userData *structs.UserData
+	rows     []row
+	visible  []row
+	selected int64
+	cursor   int
+	offset   int
+
+	width  int
+	height int
+
+	mode   mode
+	search textinput.Model
+	form   form
+	status string
+	err    error
+
+	// loading is set while a request is in flight
+	loading bool
+}
+
+// New creates the dashboard, the user data is retrieved once it starts
+func New(opts Options) Model {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	search := textinput.New()
+	search.Prompt = "Search: "
+	search.Placeholder = "name or date"
+
+	return Model{opts: opts, search: search, loading: true, width: 80, height: 24}
+}
+
+// Err returns the error that prevented the user data from ever being retrieved, if any
+func (m Model) Err() error {
+	if m.userData == nil {
+		return m.err
+	}
+	return nil
+}
+
+// Init retrieves the user data
+func (m Model) Init() tea.Cmd {
+	return m.refresh()
+}
+
+// refresh retrieves the user data and updates the offline cache with it
+func (m Model) refresh() tea.Cmd {
+	opts := m.opts
+	return func() tea.Msg {
+		userData, err := api.GetUserData(opts.URL, opts.Token)
+		if err == nil {
+			// The cache is only a fallback for the other commands, failing to update it isn't worth interrupting the dashboard
+			helper.SaveCachedUserData(opts.URL, userData)
+		}
+		return userDataMsg{userData: userData, err: err}
+	}
+}
+
+// Update handles the messages and keys
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.scroll()
+		return m, nil
+
+	case userDataMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.err = nil
+		m.userData = msg.userData
+		m.setRows()
+		return m, nil
+
+	case changeMsg:
+		if msg.err != nil {
+			m.loading = false
+			m.err = msg.err
+			m.status = ""
+			return m, nil
+		}
+		m.err = nil
+		m.status = msg.status
+		if msg.id != 0 {
+			m.selected = msg.id
+		}
+		m.loading = true
+		return m, m.refresh()
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		switch m.mode {
+		case modeSearch:
+			return m.updateSearch(msg)
+		case modeForm:
+			return m.updateForm(msg)
+		case modeConfirmDelete:
+			return m.updateConfirmDelete(msg)
+		default:
+			return m.updateBrowse(msg)
+		}
+	}
+
+	// Other messages, like the cursor blinks, go to the focused input
+	var cmd tea.Cmd
+	switch m.mode {
+	case modeSearch:
+		m.search, cmd = m.search.Update(msg)
+	case modeForm:
+		m.form.inputs[m.form.focus], cmd = m.form.inputs[m.form.focus].Update(msg)
+	}
+	return m, cmd
+}
+
+func (m Model) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		// Esc clears the search first
+		if msg.String() == "esc" && m.search.Value() != "" {
+			m.search.SetValue("")
+			m.filter()
+			return m, nil
+		}
+		return m, tea.Quit
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "pgup":
+		m.move(-m.tableHeight())
+	case "pgdown":
+		m.move(m.tableHeight())
+	case "home", "g":
+		m.move(-len(m.visible))
+	case "end", "G":
+		m.move(len(m.visible))
+	case "/":
+		m.mode = modeSearch
+		return m, m.search.Focus()
+	case "r":
+		m.loading = true
+		m.status = ""
+		return m, m.refresh()
+	case "a":
+		return m.openForm(nil)
+	case "e", "enter":
+		if birthday := m.current(); birthday != nil {
+			return m.openForm(&birthday.BirthdayFull)
+		}
+	case "d", "delete":
+		if m.current() != nil {
+			m.mode = modeConfirmDelete
+			m.status = ""
+		}
+	}
+	return m, nil
+}
+
+func (m Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.mode = modeBrowse
+		m.search.Blur()
+		return m, nil
+	case "esc":
+		m.mode = modeBrowse
+		m.search.Blur()
+		m.search.SetValue("")
+		m.filter()
+		return m, nil
+	}
+
+	// Filter as the search is typed
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.filter()
+	return m, cmd
+}
+
+func (m Model) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.mode = modeBrowse
+	birthday := m.current()
+	if birthday == nil || (msg.String() != "y" && msg.String() != "Y") {
+		m.status = "Deletion cancelled."
+		return m, nil
+	}
+
+	m.loading = true
+	opts := m.opts
+	before := birthday.BirthdayFull
+	return m, func() tea.Msg {
+		_, err := api.DeleteBirthday(opts.URL, opts.Token, structs.BirthdayNameDateModify{ID: before.ID})
+		if err != nil {
+			return changeMsg{err: fmt.Errorf("error deleting birthday: %w", err)}
+		}
+
+		var warnings bytes.Buffer
+		helper.RecordBirthdayChange(&warnings, opts.URL, helper.JournalDelete, &before, nil)
+		return changeMsg{status: withWarnings(fmt.Sprintf("Birthday with ID %d deleted successfully!", before.ID), warnings)}
+	}
+}
+
+// openForm opens the form to add a birthday, or to edit before
+func (m Model) openForm(before *structs.BirthdayFull) (tea.Model, tea.Cmd) {
+	name := textinput.New()
+	name.Prompt = "Name: "
+	name.Placeholder = "John Doe"
+	date := textinput.New()
+	date.Prompt = "Date: "
+	date.Placeholder = "YYYY-MM-DD"
+	date.CharLimit = len(helper.DateLayout)
+	m.form = form{inputs: []textinput.Model{name, date}}
+	if before != nil {
+		edited := *before
+		m.form.before = &edited
+		m.form.inputs[fieldName].SetValue(edited.Name)
+		m.form.inputs[fieldDate].SetValue(edited.Date)
+	}
+
+	m.mode = modeForm
+	m.status = ""
+	return m, m.form.inputs[fieldName].Focus()
+}
+
+func (m Model) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+	case "tab", "down":
+		return m, m.focusField((m.form.focus + 1) % len(m.form.inputs))
+	case "shift+tab", "up":
+		return m, m.focusField((m.form.focus + len(m.form.inputs) - 1) % len(m.form.inputs))
+	case "enter":
+		// Enter moves to the next field, and saves on the last one
+		if m.form.focus < len(m.form.inputs)-1 {
+			return m, m.focusField(m.form.focus + 1)
+		}
+		return m.saveForm()
+	}
+
+	var cmd tea.Cmd
+	m.form.inputs[m.form.focus], cmd = m.form.inputs[m.form.focus].Update(msg)
+	return m, cmd
+}
+
+// focusField moves the focus of the form to the field i
+func (m *Model) focusField(i int) tea.Cmd {
+	m.form.inputs[m.form.focus].Blur()
+	m.form.focus = i
+	return m.form.inputs[i].Focus()
+}
+
+// saveForm validates the form like `birthdays add` and adds or modifies the birthday
+func (m Model) saveForm() (tea.Model, tea.Cmd) {
+	name := strings.TrimSpace(m.form.inputs[fieldName].Value())
+	date := strings.TrimSpace(m.form.inputs[fieldDate].Value())
+
+	if err := helper.ValidateBirthdayName(name); err != nil {
+		m.form.err = err
+		return m, m.focusField(fieldName)
+	}
+	if err := helper.ValidateBirthdayDate(date); err != nil {
+		m.form.err = err
+		return m, m.focusField(fieldDate)
+	}
+
+	m.mode = modeBrowse
+	m.loading = true
+	opts := m.opts
+
+	// Add a new birthday
+	if m.form.before == nil {
+		return m, func() tea.Msg {
+			birthday, err := api.AddBirthday(opts.URL, opts.Token, structs.BirthdayNameDateAdd{Name: name, Date: date})
+			if err != nil {
+				return changeMsg{err: fmt.Errorf("error adding birthday: %w", err)}
+			}
+
+			var warnings bytes.Buffer
+			helper.RecordBirthdayChange(&warnings, opts.URL, helper.JournalAdd, nil, birthday)
+			return changeMsg{id: birthday.ID, status: withWarnings(fmt.Sprintf("Birthday for %s on %s added successfully!", name, date), warnings)}
+		}
+	}
+
+	// Modify the edited one
+	before := *m.form.before
+	return m, func() tea.Msg {
+		after := structs.BirthdayFull{ID: before.ID, Name: name, Date: date}
+		_, err := api.ModifyBirthday(opts.URL, opts.Token, structs.BirthdayNameDateModify{ID: after.ID, Name: name, Date: date})
+		if err != nil {
+			return changeMsg{err: fmt.Errorf("error modifying birthday: %w", err)}
+		}
+
+		var warnings bytes.Buffer
+		helper.RecordBirthdayChange(&warnings, opts.URL, helper.JournalModify, &before, &after)
+		return changeMsg{id: after.ID, status: withWarnings(fmt.Sprintf("Birthday with ID %d modified successfully to %s on %s!", after.ID, name, date), warnings)}
+	}
+}
+
+// withWarnings appends the warnings printed while recording a change to its status
+func withWarnings(status string, warnings bytes.Buffer) string {
+	if warnings.Len() == 0 {
+		return status
+	}
+	return status + " " + strings.TrimSpace(warnings.String())
+}
+
+// setRows computes the next occurrence of every birthday, soonest first, and applies the search
+func (m *Model) setRows() {
+	now := m.opts.Now()
+
+	m.rows = nil
+	for _, birthday := range m.userData.Birthdays {
+		r := row{BirthdayFull: birthday}
+		if birth, err := helper.ParseBirthdayDate(birthday.Date); err == nil {
+			r.Valid = true
+			r.DaysLeft = helper.DaysUntil(birth, now, m.opts.LeapDayPolicy)
+			r.Next = helper.NextOccurrence(birth, now, m.opts.LeapDayPolicy)
+			r.Turns = helper.Age(birth, r.Next, m.opts.LeapDayPolicy)
+		}
+		m.rows = append(m.rows, r)
+	}
+
+	// Soonest first, the birthdays with invalid dates last
+	sort.SliceStable(m.rows, func(i, j int) bool {
+		a, b := m.rows[i], m.rows[j]
+		if a.Valid != b.Valid {
+			return a.Valid
+		}
+		if a.DaysLeft != b.DaysLeft {
+			return a.DaysLeft < b.DaysLeft
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+
+	m.filter()
+}
+
+// filter keeps the rows whose name or date contain the search, and selects the same birthday as before if
+// it's still there, or the one now in its place
+func (m *Model) filter() {
+	query := strings.ToLower(strings.TrimSpace(m.search.Value()))
+
+	m.visible = nil
+	for _, r := range m.rows {
+		if query == "" || strings.Contains(strings.ToLower(r.Name), query) || strings.Contains(r.Date, query) {
+			m.visible = append(m.visible, r)
+		}
+	}
+
+	for i, r := range m.visible {
+		if r.ID == m.selected {
+			m.cursor = i
+			break
+		}
+	}
+	m.move(0)
+}
+
+// move moves the cursor by delta rows, within the table
+func (m *Model) move(delta int) {
+	m.cursor = max(0, min(m.cursor+delta, len(m.visible)-1))
+	if birthday := m.current(); birthday != nil {
+		m.selected = birthday.ID
+	}
+	m.scroll()
+}
+
+// scroll scrolls the table so the cursor is visible
+func (m *Model) scroll() {
+	height := m.tableHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.visible)-height))
+}
+
+// current returns the birthday under the cursor, nil if there's none
+func (m Model) current() *row {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return nil
+	}
+	return &m.visible[m.cursor]
+}
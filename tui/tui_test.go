@@ -0,0 +1,216 @@
+package tui
+
+import (
+	"hbd-cli/api"
+	"hbd-cli/devserver"
+	"hbd-cli/helper"
+	"hbd-cli/structs"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// testNow is the current time of the dashboard in the tests
+var testNow = time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestModel starts a dev server with an account holding birthdays, and returns a dashboard with the
+// user data loaded along with the URL and token of the account
+func newTestModel(t *testing.T, birthdays ...structs.BirthdayNameDateAdd) (Model, string, string) {
+	t.Helper()
+	t.Setenv("HBD_JOURNAL_PATH", filepath.Join(t.TempDir(), "journal"))
+	t.Setenv("HBD_CACHE_PATH", filepath.Join(t.TempDir(), "cache"))
+
+	server, err := devserver.New(devserver.Options{})
+	if err != nil {
+		t.Fatalf("devserver.New() error = %v", err)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	registered, err := api.Register(ts.URL, structs.RegisterRequest{
+		Email:             "dev@hbd.lotiguere.com",
+		Password:          "secret",
+		ReminderTime:      "09:00",
+		Timezone:          "Europe/Madrid",
+		TelegramBotAPIKey: "270485614:AAHfiqksKZ8WmR2zSjiQ7jd8Eud81ggE3e-3",
+		TelegramUserID:    "123456789",
+	}, 1)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	for _, birthday := range birthdays {
+		if _, err := api.AddBirthday(ts.URL, registered.Token, birthday); err != nil {
+			t.Fatalf("AddBirthday() error = %v", err)
+		}
+	}
+
+	m := New(Options{URL: ts.URL, Token: registered.Token, Host: "test", Days: 30, LeapDayPolicy: "feb28", Now: func() time.Time { return testNow }})
+	m = update(t, m, m.Init()())
+	if m.err != nil {
+		t.Fatalf("loading the user data error = %v", m.err)
+	}
+
+	// The user data is cached for the other commands
+	cached, err := helper.LoadCachedUserData(ts.URL)
+	if err != nil || len(cached.UserData.Birthdays) != len(birthdays) {
+		t.Fatalf("LoadCachedUserData() = %+v, %v, want the %d birthdays of the account", cached, err, len(birthdays))
+	}
+
+	return m, ts.URL, registered.Token
+}
+
+// update sends the messages to the model and waits for the requests they start. The other commands,
+// like the cursor blinks, run in the background and their messages are dropped.
+func update(t *testing.T, m Model, msgs ...tea.Msg) Model {
+	t.Helper()
+	results := make(chan tea.Msg, 16)
+	send := func(msg tea.Msg) {
+		model, cmd := m.Update(msg)
+		m = model.(Model)
+		if cmd != nil {
+			go func() {
+				switch msg := cmd().(type) {
+				case userDataMsg, changeMsg:
+					results <- msg
+				}
+			}()
+		}
+	}
+
+	for _, msg := range msgs {
+		send(msg)
+		for m.loading {
+			select {
+			case msg := <-results:
+				send(msg)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("no response to the request started by %v", msg)
+			}
+		}
+	}
+
+	return m
+}
+
+// keys returns the messages of the keys, special keys are written like <enter>
+func keys(s string) []tea.Msg {
+	special := map[string]tea.KeyType{"<enter>": tea.KeyEnter, "<tab>": tea.KeyTab, "<esc>": tea.KeyEsc, "<down>": tea.KeyDown}
+
+	var msgs []tea.Msg
+	for s != "" {
+		if name, _, ok := strings.Cut(s, ">"); ok && special[name+">"] != 0 {
+			msgs = append(msgs, tea.KeyMsg{Type: special[name+">"]})
+			s = s[len(name)+1:]
+			continue
+		}
+		r := []rune(s)[0]
+		msgs = append(msgs, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		s = s[len(string(r)):]
+	}
+
+	return msgs
+}
+
+func names(rows []row) []string {
+	var names []string
+	for _, r := range rows {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestModelRows(t *testing.T) {
+	m, _, _ := newTestModel(t,
+		structs.BirthdayNameDateAdd{Name: "Winter", Date: "1980-12-24"},
+		structs.BirthdayNameDateAdd{Name: "Soon", Date: "1990-06-10"},
+		structs.BirthdayNameDateAdd{Name: "Today", Date: "2000-06-01"},
+	)
+
+	if got, want := strings.Join(names(m.visible), ","), "Today,Soon,Winter"; got != want {
+		t.Errorf("rows = %s, want %s", got, want)
+	}
+	if r := m.visible[1]; r.DaysLeft != 9 || r.Turns != 34 {
+		t.Errorf("Soon days left, turns = %d, %d, want 9, 34", r.DaysLeft, r.Turns)
+	}
+
+	view := m.View()
+	for _, want := range []string{"3 birthdays", "09:00", "Europe/Madrid", "Today", "today", "9 days", "Winter"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() doesn't contain %q:\n%s", want, view)
+		}
+	}
+
+	// The search filters by name or date
+	m = update(t, m, keys("/win")...)
+	if got := strings.Join(names(m.visible), ","); got != "Winter" {
+		t.Errorf("rows matching win = %s, want Winter", got)
+	}
+	m = update(t, m, keys("<esc>")...)
+	if len(m.visible) != 3 {
+		t.Errorf("rows after clearing the search = %d, want 3", len(m.visible))
+	}
+}
+
+func TestModelChanges(t *testing.T) {
+	m, url, token := newTestModel(t, structs.BirthdayNameDateAdd{Name: "John Doe", Date: "1990-01-02"})
+
+	// The form is validated like `birthdays add`
+	m = update(t, m, keys("aJane Doe<tab>2999-01-01<enter>")...)
+	if m.mode != modeForm || m.form.err == nil {
+		t.Fatalf("saving a future date: mode = %v, error = %v, want the form with an error", m.mode, m.form.err)
+	}
+
+	// Fixing the date adds the birthday and selects it
+	for range "2999-01-01" {
+		m = update(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	m = update(t, m, keys("1991-03-04<enter>")...)
+	if m.err != nil || m.mode != modeBrowse {
+		t.Fatalf("adding: mode = %v, error = %v", m.mode, m.err)
+	}
+	if got := strings.Join(names(m.visible), ","); got != "Jane Doe,John Doe" && got != "John Doe,Jane Doe" {
+		t.Fatalf("rows after adding = %s", got)
+	}
+	if m.current().Name != "Jane Doe" {
+		t.Errorf("selected after adding = %s, want Jane Doe", m.current().Name)
+	}
+
+	// Editing keeps the other field
+	m = update(t, m, keys("e<tab>")...)
+	m = update(t, m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = update(t, m, keys("1991-03-05<enter>")...)
+	if m.err != nil || m.current().Date != "1991-03-05" || m.current().Name != "Jane Doe" {
+		t.Fatalf("editing: selected = %+v, error = %v", m.current(), m.err)
+	}
+
+	// Deleting asks for confirmation
+	m = update(t, m, keys("dn")...)
+	if len(m.rows) != 2 {
+		t.Fatalf("rows after cancelling the deletion = %d, want 2", len(m.rows))
+	}
+	m = update(t, m, keys("dy")...)
+	if m.err != nil || len(m.rows) != 1 {
+		t.Fatalf("deleting: rows = %d, error = %v", len(m.rows), m.err)
+	}
+
+	// Every change went to the server and the journal
+	userData, err := api.GetUserData(url, token)
+	if err != nil || len(userData.Birthdays) != 1 || userData.Birthdays[0].Name != "John Doe" {
+		t.Errorf("GetUserData() = %+v, %v, want only John Doe", userData, err)
+	}
+	journal, err := helper.LoadJournal(helper.JournalFilePath(url))
+	if err != nil {
+		t.Fatalf("LoadJournal() error = %v", err)
+	}
+	var operations []string
+	for _, entry := range journal {
+		operations = append(operations, entry.Operation)
+	}
+	if got := strings.Join(operations, ","); got != "add,modify,delete" {
+		t.Errorf("journal = %s, want add,modify,delete", got)
+	}
+}
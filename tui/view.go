@@ -0,0 +1,206 @@
+package tui
+
+import (
+	"fmt"
+	"hbd-cli/helper"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// sideWidth is the width of the account panel, borders included
+const sideWidth = 30
+
+// Widths of the columns of the table, the name takes the rest
+const (
+	idWidth    = 5
+	dateWidth  = 10
+	inWidth    = 8
+	turnsWidth = 5
+	minName    = 8
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	panelStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8"))
+	headerStyle   = lipgloss.NewStyle().Bold(true).Underline(true)
+	upcomingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true)
+	cursorStyle   = lipgloss.NewStyle().Reverse(true)
+	labelStyle    = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+	statusStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	helpStyle     = lipgloss.NewStyle().Faint(true)
+)
+
+// View renders the dashboard: the title, the search, the table or form next to the account panel,
+// the status and the keys
+func (m Model) View() string {
+	title := titleStyle.Render("hbd · " + m.opts.Host)
+	if m.userData != nil {
+		title += titleStyle.Render(fmt.Sprintf(" · %d birthdays", len(m.rows)))
+	}
+
+	var main string
+	if m.mode == modeForm {
+		main = m.viewForm()
+	} else {
+		main = m.viewTable()
+	}
+	panels := lipgloss.JoinHorizontal(lipgloss.Top,
+		panelStyle.Width(m.tableWidth()).Height(m.tableHeight()+1).Render(main),
+		" ",
+		panelStyle.Width(sideWidth-2).Height(m.tableHeight()+1).Render(m.viewAccount()),
+	)
+
+	return strings.Join([]string{title, m.search.View(), panels, m.viewStatus(), m.viewHelp()}, "\n")
+}
+
+// tableWidth returns the width inside the borders of the table
+func (m Model) tableWidth() int {
+	return max(idWidth+dateWidth*2+inWidth+turnsWidth+minName+5, m.width-sideWidth-1-2)
+}
+
+// tableHeight returns the number of rows shown by the table
+func (m Model) tableHeight() int {
+	// The title, search, borders, header, status and keys take 7 lines
+	return max(1, m.height-7)
+}
+
+func (m Model) viewTable() string {
+	nameWidth := m.tableWidth() - idWidth - dateWidth*2 - inWidth - turnsWidth - 5
+	line := func(id, name, date, next, in, turns string) string {
+		return strings.Join([]string{fit(id, idWidth), fit(name, nameWidth), fit(date, dateWidth), fit(next, dateWidth), fit(in, inWidth), fit(turns, turnsWidth)}, " ")
+	}
+
+	lines := []string{headerStyle.Render(line("ID", "Name", "Date", "Next", "In", "Turns"))}
+	switch {
+	case m.userData == nil && m.loading:
+		lines = append(lines, "Loading birthdays...")
+	case len(m.rows) == 0 && m.userData != nil:
+		lines = append(lines, "No birthdays yet, press a to add one.")
+	case len(m.visible) == 0 && m.userData != nil:
+		lines = append(lines, "No birthdays match the search.")
+	}
+
+	end := min(len(m.visible), m.offset+m.tableHeight())
+	for i := m.offset; i < end; i++ {
+		r := m.visible[i]
+		next, in, turns := "-", "-", "-"
+		if r.Valid {
+			next = r.Next.Format(helper.DateLayout)
+			in = daysLeft(r.DaysLeft)
+			turns = strconv.Itoa(r.Turns)
+		}
+
+		text := line(strconv.FormatInt(r.ID, 10), r.Name, r.Date, next, in, turns)
+		style := lipgloss.NewStyle()
+		if r.Valid && r.DaysLeft <= m.opts.Days {
+			style = upcomingStyle
+		}
+		if i == m.cursor {
+			style = style.Inherit(cursorStyle)
+		}
+		lines = append(lines, style.Render(text))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m Model) viewForm() string {
+	title := "Add a birthday"
+	if m.form.before != nil {
+		title = fmt.Sprintf("Edit the birthday with ID %d", m.form.before.ID)
+	}
+
+	lines := []string{headerStyle.Render(title), ""}
+	for _, input := range m.form.inputs {
+		lines = append(lines, input.View())
+	}
+	if m.form.err != nil {
+		lines = append(lines, "", errorStyle.Render("Error: "+m.form.err.Error()))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m Model) viewAccount() string {
+	width := sideWidth - 4
+	lines := []string{headerStyle.Render("Account"), ""}
+	field := func(label, value string) {
+		lines = append(lines, labelStyle.Render(label), "  "+fit(value, width-2), "")
+	}
+
+	if m.userData == nil {
+		field("Reminder time", "-")
+		field("Timezone", "-")
+		return strings.Join(lines, "\n")
+	}
+
+	field("Reminder time", m.userData.ReminderTime)
+	field("Timezone", m.userData.Timezone)
+
+	upcoming := 0
+	for _, r := range m.rows {
+		if r.Valid && r.DaysLeft <= m.opts.Days {
+			upcoming++
+		}
+	}
+	field(fmt.Sprintf("Upcoming (%d days)", m.opts.Days), strconv.Itoa(upcoming))
+	if len(m.rows) > 0 && m.rows[0].Valid {
+		field("Next birthday", fmt.Sprintf("%s, %s", m.rows[0].Name, daysLeft(m.rows[0].DaysLeft)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m Model) viewStatus() string {
+	switch {
+	case m.mode == modeConfirmDelete:
+		r := m.current()
+		return errorStyle.Render(fmt.Sprintf("Delete the birthday of %s on %s? This action cannot be undone. (y/N)", r.Name, r.Date))
+	case m.err != nil:
+		return errorStyle.Render("Error: " + m.err.Error())
+	case m.status != "":
+		return statusStyle.Render(m.status)
+	case m.loading:
+		return labelStyle.Render("Loading...")
+	}
+	return ""
+}
+
+func (m Model) viewHelp() string {
+	switch m.mode {
+	case modeSearch:
+		return helpStyle.Render("type to search • enter done • esc clear")
+	case modeForm:
+		return helpStyle.Render("tab next field • enter save • esc cancel")
+	case modeConfirmDelete:
+		return helpStyle.Render("y delete • any other key cancel")
+	}
+	return helpStyle.Render("↑/↓ move • / search • a add • e edit • d delete • r refresh • q quit")
+}
+
+// daysLeft describes how many days are left until a birthday
+func daysLeft(days int) string {
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// fit truncates s to width cells, ending it with an ellipsis, or pads it with spaces
+func fit(s string, width int) string {
+	if lipgloss.Width(s) > width {
+		runes := []rune(s)
+		for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+			runes = runes[:len(runes)-1]
+		}
+		s = string(runes) + "…"
+	}
+
+	return s + strings.Repeat(" ", max(0, width-lipgloss.Width(s)))
+}
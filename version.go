@@ -61,5 +61,5 @@ func CheckForNewVersion() string {
 // Print the current version of the CLI
 func HBDCLIVersion() string {
 	c := color.New(color.FgBlue, color.Bold)
-	return c.Sprintf(` hbd-cli version: %s`+"\n", Version) + CheckForNewVersion()
+	return c.Sprintf(` hbd-cli version: %s`+"\n", Version)
 }